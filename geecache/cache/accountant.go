@@ -0,0 +1,145 @@
+package cache
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// memoryUser 是 Accountant 管理的成员：一个分片，或者一个子 Accountant
+type memoryUser interface {
+	usedBytes() int64
+	weight() int64   // 在父 Accountant 中所占份额的权重
+	evictOne() int64 // 淘汰一条数据，返回释放的字节数，没有可淘汰的数据时返回 0
+}
+
+// Accountant 统计一组成员的内存占用，总量超过预算时，
+// 从超出自身公平份额最多的成员中淘汰数据，直到回到预算以内。
+//
+// 每个 ShardedCache 有一个 Accountant，成员是它的分片，分片的份额相同；
+// 多个 ShardedCache（例如进程中的所有 Group）可以共享一个父 Accountant，
+// 父 Accountant 的成员是各个子 Accountant，份额按子 Accountant 的预算分配。
+type Accountant struct {
+	budget int64 // 0 表示不限制，只统计
+	used   atomic.Int64
+	parent atomic.Pointer[Accountant]
+
+	mu      sync.Mutex // 保护 members，同时保证同一时刻只有一个协程在淘汰
+	members []memoryUser
+}
+
+// NewAccountant 创建预算为 budget 字节的 Accountant，budget 为 0 表示不限制。
+// parent 不为 nil 时，新 Accountant 的内存占用同时计入 parent。
+func NewAccountant(budget int64, parent *Accountant) *Accountant {
+	a := &Accountant{budget: budget}
+	if parent != nil {
+		a.parent.Store(parent)
+		parent.add(a)
+	}
+	return a
+}
+
+// Budget 返回预算，0 表示不限制
+func (a *Accountant) Budget() int64 {
+	return a.budget
+}
+
+// Used 返回所有成员当前占用的字节数
+func (a *Accountant) Used() int64 {
+	return a.used.Load()
+}
+
+// Detach 把 a 从父 Accountant 中移除，a 的内存占用不再计入父 Accountant
+func (a *Accountant) Detach() {
+	parent := a.parent.Swap(nil)
+	if parent == nil {
+		return
+	}
+	parent.remove(a)
+	parent.charge(-a.used.Load())
+}
+
+func (a *Accountant) add(m memoryUser) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.members = append(a.members, m)
+}
+
+func (a *Accountant) remove(m memoryUser) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if i := slices.Index(a.members, m); i >= 0 {
+		a.members = slices.Delete(a.members, i, i+1)
+	}
+}
+
+// charge 把 delta 计入 a 及其所有祖先，只更新计数，不淘汰
+func (a *Accountant) charge(delta int64) {
+	for acc := a; acc != nil && delta != 0; acc = acc.parent.Load() {
+		acc.used.Add(delta)
+	}
+}
+
+// enforce 从 a 开始逐级向上检查预算，超出时淘汰数据。
+// 调用方不能持有任何分片的锁。
+func (a *Accountant) enforce() {
+	for acc := a; acc != nil; acc = acc.parent.Load() {
+		if acc.budget > 0 && acc.used.Load() > acc.budget {
+			acc.reclaim()
+		}
+	}
+}
+
+// reclaim 反复从超出公平份额最多的成员中淘汰数据，直到回到预算以内或者无法再淘汰
+func (a *Accountant) reclaim() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for a.used.Load() > a.budget {
+		victim := a.mostOverLocked()
+		if victim == nil || victim.evictOne() == 0 {
+			return
+		}
+	}
+}
+
+// evictOne 作为父 Accountant 的成员时，从 a 中超出份额最多的成员淘汰一条数据
+func (a *Accountant) evictOne() int64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if victim := a.mostOverLocked(); victim != nil {
+		return victim.evictOne()
+	}
+	return 0
+}
+
+// mostOverLocked 返回占用超出公平份额最多的成员，份额按权重分配预算；没有占用内存的成员不会被选中
+func (a *Accountant) mostOverLocked() memoryUser {
+	var total int64
+	for _, m := range a.members {
+		total += m.weight()
+	}
+	var (
+		victim memoryUser
+		most   float64
+	)
+	for _, m := range a.members {
+		used := m.usedBytes()
+		if used <= 0 {
+			continue
+		}
+		over := float64(used) - float64(a.budget)*float64(m.weight())/float64(total)
+		if victim == nil || over > most {
+			victim, most = m, over
+		}
+	}
+	return victim
+}
+
+func (a *Accountant) usedBytes() int64 {
+	return a.used.Load()
+}
+
+// weight 是子 Accountant 在父 Accountant 中的权重：按预算分配份额，不限制预算的子 Accountant 份额最小
+func (a *Accountant) weight() int64 {
+	return max(a.budget, 1)
+}
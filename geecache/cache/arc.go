@@ -0,0 +1,168 @@
+package cache
+
+import (
+	"GeeCache/geecache/common"
+	"container/list"
+	"time"
+)
+
+// ARC 的两个段
+const (
+	arcT1 = iota // 只访问过一次的条目
+	arcT2        // 访问过至少两次的条目
+)
+
+// ARCCache 是一个 ARC（Adaptive Replacement Cache）缓存，非并发安全。
+//
+// 缓存分为 T1、T2 两个 LRU 段：新条目进入 T1，再次访问后移入 T2。
+// B1、B2 两个幽灵链表分别记录最近从 T1、T2 淘汰的 key。
+// 写入 B1 中的 key 说明 T1 太小，于是增大 T1 的目标大小 p；写入 B2 中的 key 则减小 p。
+// 这样在偏重最近访问和偏重访问频率的负载之间自动调整。
+// p 和幽灵链表都按字节计算：幽灵链表记录条目被淘汰前的大小，合计不超过容量，与 LRUCache 的 maxBytes 一致。
+//
+// maxBytes 为 0 时不主动淘汰，由调用方（例如 Accountant）调用 RemoveOldest，
+// 此时按 SetCapacity 设置的容量计算，没有设置时按当前占用的字节数作为容量。
+type ARCCache struct {
+	maxBytes int64
+	segments           // T1 和 T2
+	b1, b2   ghostList // 从 T1、T2 淘汰的 key
+	p        int64     // T1 的目标字节数
+
+	// 某条记录被移除时的回调函数，可为 nil
+	OnEvicted func(key string, value common.Value)
+}
+
+// NewARCCache 创建一个 ARC 缓存实例，maxBytes 为 0 表示不限制
+func NewARCCache(maxBytes int64, onEvicted func(string, common.Value)) *ARCCache {
+	return &ARCCache{
+		maxBytes:  maxBytes,
+		segments:  newSegments(2),
+		b1:        newGhostList(),
+		b2:        newGhostList(),
+		OnEvicted: onEvicted,
+	}
+}
+
+// Get 获取缓存条目，命中的条目移入 T2
+func (c *ARCCache) Get(key string) (value common.Value, ok bool) {
+	ele, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	e := ele.Value.(*segEntry)
+	// 已过期的条目视为未命中，并惰性删除
+	if expired(e.expire, time.Now()) {
+		c.removeElement(ele)
+		return nil, false
+	}
+	c.touch(ele)
+	return e.value, true
+}
+
+// touch 把条目移到 T2 的最前面
+func (c *ARCCache) touch(ele *list.Element) {
+	if ele.Value.(*segEntry).seg == arcT1 {
+		c.moveTo(ele, arcT2)
+	} else {
+		c.lists[arcT2].MoveToFront(ele)
+	}
+}
+
+// Add 添加或更新缓存条目
+func (c *ARCCache) Add(key string, value common.Value) {
+	c.AddWithExpire(key, value, time.Time{})
+}
+
+// AddWithExpire 添加或更新一个在 expire 时刻过期的条目，expire 为零值表示永不过期。
+// key 在幽灵链表中时按命中的链表调整 p，并直接放入 T2。
+func (c *ARCCache) AddWithExpire(key string, value common.Value, expire time.Time) {
+	if ele, ok := c.items[key]; ok {
+		c.update(ele, value, expire)
+		c.touch(ele)
+	} else {
+		size := int64(len(key)) + int64(value.Len())
+		switch {
+		case c.b1.contains(key):
+			// B 链表越小，说明这一侧越少被误淘汰，每次命中调整得越多
+			c.p = min(c.p+max(size, size*c.b2.bytes/c.b1.bytes), c.capacity())
+			c.b1.remove(key)
+			c.push(arcT2, key, value, expire)
+		case c.b2.contains(key):
+			c.p = max(c.p-max(size, size*c.b1.bytes/c.b2.bytes), 0)
+			c.b2.remove(key)
+			c.push(arcT2, key, value, expire)
+		default:
+			c.push(arcT1, key, value, expire)
+		}
+	}
+	for c.maxBytes != 0 && c.maxBytes < c.nbytes {
+		c.RemoveOldest()
+	}
+	c.trimGhosts()
+}
+
+// RemoveOldest 淘汰一个条目：T1 超过目标大小 p 时淘汰 T1 中最久未访问的条目，否则淘汰 T2 中的，
+// 被淘汰的 key 记入对应的幽灵链表
+func (c *ARCCache) RemoveOldest() {
+	t1, t2 := c.lists[arcT1], c.lists[arcT2]
+	switch {
+	case t1.Len() > 0 && (c.bytes[arcT1] > c.p || t2.Len() == 0):
+		e := c.removeElement(t1.Back())
+		c.b1.push(e.key, e.size())
+	case t2.Len() > 0:
+		e := c.removeElement(t2.Back())
+		c.b2.push(e.key, e.size())
+	default:
+		return
+	}
+	c.trimGhosts()
+}
+
+// trimGhosts 限制幽灵链表的大小：T1 与 B1 合计不超过容量，所有链表合计不超过容量的两倍
+func (c *ARCCache) trimGhosts() {
+	capacity := c.capacity()
+	c.b1.trim(capacity - c.bytes[arcT1])
+	c.b2.trim(2*capacity - c.nbytes - c.b1.bytes)
+}
+
+// capacity 返回容量，不限制容量时使用 SetCapacity 设置的容量，见 segments.limit
+func (c *ARCCache) capacity() int64 {
+	return c.limit(c.maxBytes)
+}
+
+// Bytes 返回当前占用的字节数，不包括幽灵链表
+func (c *ARCCache) Bytes() int64 {
+	return c.nbytes
+}
+
+// Remove 删除指定 key 的条目，返回该 key 是否存在
+func (c *ARCCache) Remove(key string) bool {
+	if ele, ok := c.items[key]; ok {
+		c.removeElement(ele)
+		return true
+	}
+	return false
+}
+
+// RemoveExpired 清理所有已过期的条目，返回清理的条目数
+func (c *ARCCache) RemoveExpired() int {
+	eles := c.expired(time.Now())
+	for _, ele := range eles {
+		c.removeElement(ele)
+	}
+	return len(eles)
+}
+
+// removeElement 删除节点并触发 OnEvicted 回调，返回被删除的条目
+func (c *ARCCache) removeElement(ele *list.Element) *segEntry {
+	e := c.remove(ele)
+	if c.OnEvicted != nil {
+		c.OnEvicted(e.key, e.value)
+	}
+	return e
+}
+
+// Len 返回缓存条目数
+func (c *ARCCache) Len() int {
+	return len(c.items)
+}
@@ -0,0 +1,153 @@
+package cache
+
+import (
+	"GeeCache/geecache/common"
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// scanWorkload 先在较短的间隔内反复访问 30 个热点 key，之后每两次访问之间穿插超过容量（约 100 个条目）的一次性扫描，
+// 返回最后一轮热点 key 的命中数。c 可以是淘汰策略，也可以是 ConcurrentCache。
+func scanWorkload(c interface {
+	Get(key string) (common.Value, bool)
+	Add(key string, value common.Value)
+}) (hits int) {
+	scan := 0
+	for round := 0; round < 15; round++ {
+		hits = 0
+		for i := 0; i < 30; i++ {
+			key := fmt.Sprintf("hot%04d", i)
+			if _, ok := c.Get(key); ok {
+				hits++
+			} else {
+				c.Add(key, String("v"))
+			}
+		}
+		scanLen := 30
+		if round >= 5 {
+			scanLen = 150
+		}
+		for i := 0; i < scanLen; i++ {
+			key := fmt.Sprintf("s%06d", scan)
+			scan++
+			if _, ok := c.Get(key); !ok {
+				c.Add(key, String("v"))
+			}
+		}
+	}
+	return hits
+}
+
+// ARC 和 2Q 应保留大部分热点，LRU 会被扫描冲掉
+func TestAdaptivePoliciesResistScans(t *testing.T) {
+	const capacity = 100 * 8 // 约 100 个条目
+	if hits := scanWorkload(NewLRUCache(capacity, nil)); hits != 0 {
+		t.Fatalf("expected the scan to flush lru, got %d hot hits", hits)
+	}
+	for _, algorithm := range []string{"arc", "2q"} {
+		if hits := scanWorkload(NewCache(algorithm, capacity)); hits < 27 {
+			t.Errorf("%s: expected most of the 30 hot keys to survive scans, got %d hits", algorithm, hits)
+		}
+	}
+}
+
+// 在 ConcurrentCache 中由 Accountant 限制容量时，策略按分片的份额计算幽灵链表和各段的大小，
+// 与自己限制容量时一样能抵抗扫描
+func TestAdaptivePoliciesUnderAccountant(t *testing.T) {
+	const capacity = 100 * 8
+	for _, algorithm := range []string{"arc", "2q"} {
+		if hits := scanWorkload(NewConcurrentCache(capacity, algorithm)); hits < 27 {
+			t.Errorf("%s: expected most of the 30 hot keys to survive scans, got %d hits", algorithm, hits)
+		}
+	}
+}
+
+// 缓存中的条目被删除（例如过期后被后台协程清理）后，幽灵链表仍按分片的份额保留淘汰历史，
+// 而不是按删除后剩下的字节数截断：最近淘汰的 key 再次写入时仍被识别为反复访问的 key
+func TestAdaptivePoliciesKeepHistoryUnderAccountant(t *testing.T) {
+	for algorithm, want := range map[string]struct {
+		evicted string // 最后被淘汰的 key
+		seg     int    // 幽灵链表命中后进入的段
+	}{"arc": {"s000099", arcT2}, "2q": {"s000049", twoQMain}} {
+		c := NewConcurrentCache(100*8, algorithm)
+		for i := 0; i < 50; i++ {
+			key := fmt.Sprintf("f%06d", i)
+			c.Add(key, String("v"))
+			c.Get(key)
+		}
+		// 一次性的 key 挤掉最早写入的一批，被淘汰的 key 记入幽灵链表
+		for i := 0; i < 150; i++ {
+			c.Add(fmt.Sprintf("s%06d", i), String("v"))
+		}
+		for _, key := range c.Keys() {
+			c.Remove(key)
+		}
+		c.Add("x000000", String("v")) // 写入新条目时按容量截断幽灵链表
+		c.Add(want.evicted, String("v"))
+		var s *segments
+		switch p := c.cache.(type) {
+		case *ARCCache:
+			s = &p.segments
+		case *TwoQueueCache:
+			s = &p.segments
+		}
+		if ele, ok := s.items[want.evicted]; !ok || ele.Value.(*segEntry).seg != want.seg {
+			t.Errorf("%s: expected %s to hit the ghost list after removals", algorithm, want.evicted)
+		}
+	}
+}
+
+// ARC 在访问模式从偏重频率切换到偏重最近访问后调整 T1 的目标大小
+func TestARCAdapts(t *testing.T) {
+	c := NewARCCache(100*8, nil)
+	// 反复访问同一批 key，它们进入 T2；随后循环访问 80 个 key，T1 装不下它们，被淘汰的 key 很快再次写入，命中 B1
+	for i := 0; i < 50; i++ {
+		key := fmt.Sprintf("f%06d", i)
+		c.Add(key, String("v"))
+		c.Get(key)
+	}
+	for i := 0; i < 2000; i++ {
+		key := fmt.Sprintf("r%06d", i%80)
+		if _, ok := c.Get(key); !ok {
+			c.Add(key, String("v"))
+		}
+	}
+	if c.p == 0 {
+		t.Fatal("expected ghost hits in B1 to grow the T1 target")
+	}
+	// T1 扩大之后循环访问的 key 全部留在缓存中
+	for i := 0; i < 80; i++ {
+		if _, ok := c.Get(fmt.Sprintf("r%06d", i)); !ok {
+			t.Fatalf("expected r%06d to be cached after adapting, p=%d", i, c.p)
+		}
+	}
+}
+
+// 随机操作过程中缓存和幽灵链表都不超过按字节计算的上限
+func TestAdaptivePoliciesBounds(t *testing.T) {
+	const capacity = 1024
+	arc, twoQ := NewARCCache(capacity, nil), NewTwoQueueCache(capacity, nil)
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 20000; i++ {
+		key := fmt.Sprintf("key%d", r.Intn(300))
+		for _, c := range []Cache{arc, twoQ} {
+			switch r.Intn(5) {
+			case 0:
+				c.Remove(key)
+			case 1, 2:
+				c.Get(key)
+			default:
+				c.Add(key, String(make([]byte, r.Intn(48))))
+			}
+		}
+		if arc.Bytes() > capacity || arc.bytes[arcT1]+arc.b1.bytes > capacity ||
+			arc.nbytes+arc.b1.bytes+arc.b2.bytes > 2*capacity || arc.p < 0 || arc.p > capacity {
+			t.Fatalf("arc out of bounds: T1=%d T2=%d B1=%d B2=%d p=%d",
+				arc.bytes[arcT1], arc.bytes[arcT2], arc.b1.bytes, arc.b2.bytes, arc.p)
+		}
+		if twoQ.Bytes() > capacity || twoQ.out.bytes > capacity*twoQOutPercent/100 {
+			t.Fatalf("2q out of bounds: A1in=%d Am=%d A1out=%d", twoQ.bytes[twoQIn], twoQ.bytes[twoQMain], twoQ.out.bytes)
+		}
+	}
+}
@@ -4,14 +4,18 @@ import (
 	"GeeCache/geecache/common"
 	"GeeCache/geecache/data"
 	"GeeCache/geecache/interfaces"
+	"fmt"
 	"hash/fnv"
+	"time"
 
 	"sync"
+	"sync/atomic"
 )
 
 type Cache interface {
 	Get(key string) (common.Value, bool)
 	Add(key string, value common.Value)
+	Remove(key string) bool
 	Len() int
 }
 
@@ -19,45 +23,117 @@ type Cache interface {
 type ConcurrentCache struct {
 	mu         sync.Mutex // 互斥锁
 	cache      interfaces.EvictionPolicy
-	CacheBytes int64  // 字段名首字母大写表示它是导出的，可以在其他包中访问
+	CacheBytes int64  // 字段名首字母大写表示它是导出的，可以在其他包中访问。属于 ShardedCache 时为平分后的份额，实际由 Accountant 限制
 	Algorithm  string // 新增字段，用于指定算法类型
+
+	acc *Accountant // 限制容量的 Accountant，属于 ShardedCache 时由所有分片共享
+
+	// 以下统计不加锁即可读取
+	bytes     atomic.Int64 // 当前占用的字节数
+	items     atomic.Int64 // 当前条目数
+	hits      atomic.Int64
+	misses    atomic.Int64
+	evictions atomic.Int64 // 因超出容量被淘汰的条目数
 }
 
 // ShardedCache 分片缓存，提升并发性能, 一个包含多个 ConcurrentCache 实例的缓存系统
 type ShardedCache struct {
 	Shards    []*ConcurrentCache
 	NumShards int
+	acc       *Accountant // 所有分片共享的内存预算
+
+	janitorMu sync.Mutex
+	stop      chan struct{} // 关闭后后台清理协程退出，nil 表示清理协程未启动
 }
 
-// NewConcurrentCache 创建一个并发缓存，支持动态选择算法
+// NewConcurrentCache 创建一个并发缓存，支持动态选择算法，算法未注册时 panic
 func NewConcurrentCache(cacheBytes int64, algorithm string) *ConcurrentCache {
-	var cache interfaces.EvictionPolicy
-	switch algorithm {
-	case "lru":
-		cache = NewLRUCache(cacheBytes, nil)
-	case "lfu":
-		cache = NewLFUCache(int(cacheBytes))
-	default:
-		panic("unsupported cache algorithm")
+	c, err := newConcurrentCache(cacheBytes, algorithm, nil, NewAccountant(cacheBytes, nil))
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// newConcurrentCache 创建由 acc 限制容量的分片并加入 acc，淘汰策略本身不限制容量，
+// 这样所有因容量产生的淘汰都经过 evictOne，便于统计。
+// 策略实现了 interfaces.CapacitySetter 时把分片的份额 cacheBytes 告诉策略。
+func newConcurrentCache(cacheBytes int64, algorithm string, onEvicted func(string, common.Value), acc *Accountant) (*ConcurrentCache, error) {
+	cache, err := NewPolicy(algorithm, 0, onEvicted)
+	if err != nil {
+		return nil, err
 	}
-	return &ConcurrentCache{
+	if cs, ok := cache.(interfaces.CapacitySetter); ok && cacheBytes > 0 {
+		cs.SetCapacity(cacheBytes)
+	}
+	c := &ConcurrentCache{
 		CacheBytes: cacheBytes,
 		cache:      cache,
 		Algorithm:  algorithm,
+		acc:        acc,
 	}
+	acc.add(c)
+	return c, nil
 }
 
 // 将缓存分片（sharding），ShardedCache 可以提高并发性能，因为不同的 Goroutine 可以访问不同的缓存分片，避免了全局锁竞争。
+// 参数不合法时 panic，需要返回错误时使用 NewShardedCacheWithConfig。
 func NewShardedCache(numShards int, cacheBytes int64, algorithm string) *ShardedCache {
-	// shards 切片保存每个分片的 ConcurrentCache 实例。
-	shards := make([]*ConcurrentCache, numShards)
-	for i := 0; i < numShards; i++ {
-		shards[i] = NewConcurrentCache(cacheBytes, algorithm)
+	s, err := NewShardedCacheWithConfig(ShardedCacheConfig{Shards: numShards, CacheBytes: cacheBytes, Algorithm: algorithm})
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// ShardedCacheConfig 是创建分片缓存的参数
+type ShardedCacheConfig struct {
+	Shards     int                                  // 分片数，必须大于 0
+	CacheBytes int64                                // 所有分片合计的容量，0 表示不限制
+	Algorithm  string                               // 淘汰算法，见 NewPolicy
+	OnEvicted  func(key string, value common.Value) // 条目被删除时调用，可为 nil
+	Parent     *Accountant                          // 不为 nil 时内存占用同时计入 Parent 的预算，例如进程级的预算
+}
+
+// NewShardedCacheWithConfig 按 cfg 创建分片缓存，参数不合法时返回错误。
+// 分片本身不限制容量，由共享的 Accountant 保证所有分片合计不超过 CacheBytes。
+func NewShardedCacheWithConfig(cfg ShardedCacheConfig) (*ShardedCache, error) {
+	if cfg.Shards <= 0 {
+		return nil, fmt.Errorf("shard count must be positive, got %d", cfg.Shards)
+	}
+	if cfg.CacheBytes < 0 {
+		return nil, fmt.Errorf("cache size must not be negative, got %d", cfg.CacheBytes)
+	}
+	if _, err := NewPolicy(cfg.Algorithm, 0, nil); err != nil {
+		return nil, err
+	}
+	// shards 切片保存每个分片的 ConcurrentCache 实例，CacheBytes 为平分后的份额
+	acc := NewAccountant(cfg.CacheBytes, cfg.Parent)
+	shards := make([]*ConcurrentCache, cfg.Shards)
+	for i := range shards {
+		shards[i], _ = newConcurrentCache(cfg.CacheBytes/int64(cfg.Shards), cfg.Algorithm, cfg.OnEvicted, acc)
 	}
 	return &ShardedCache{
 		Shards:    shards,
-		NumShards: numShards,
-	}
+		NumShards: cfg.Shards,
+		acc:       acc,
+	}, nil
+}
+
+// Bytes 返回所有分片当前占用的字节数
+func (s *ShardedCache) Bytes() int64 {
+	return s.acc.Used()
+}
+
+// Accountant 返回所有分片共享的 Accountant
+func (s *ShardedCache) Accountant() *Accountant {
+	return s.acc
+}
+
+// Close 停止后台清理协程，并把缓存从父 Accountant 中移除
+func (s *ShardedCache) Close() {
+	s.StopJanitor()
+	s.acc.Detach()
 }
 
 // getShard 根据键计算对应的分片
@@ -67,10 +143,56 @@ func (s *ShardedCache) GetShard(key string) *ConcurrentCache {
 	return s.Shards[int(h.Sum32())%s.NumShards]
 }
 
+// unlockAndCharge 释放锁，并把本次操作引起的内存占用变化计入 Accountant；占用增加时检查预算。
+// 必须在释放锁之后检查预算，因为淘汰时会锁住其他分片。
+func (c *ConcurrentCache) unlockAndCharge() {
+	n := c.cache.Bytes()
+	delta := n - c.bytes.Swap(n)
+	c.items.Store(int64(c.cache.Len()))
+	c.mu.Unlock()
+	if delta != 0 {
+		c.acc.charge(delta)
+		if delta > 0 {
+			c.acc.enforce()
+		}
+	}
+}
+
+// Bytes 返回当前占用的字节数
+func (c *ConcurrentCache) Bytes() int64 {
+	return c.bytes.Load()
+}
+
+func (c *ConcurrentCache) usedBytes() int64 {
+	return c.bytes.Load()
+}
+
+// weight 分片之间平分所属 ShardedCache 的预算
+func (c *ConcurrentCache) weight() int64 {
+	return 1
+}
+
+// evictOne 按淘汰策略删除一条数据，返回释放的字节数
+func (c *ConcurrentCache) evictOne() int64 {
+	c.mu.Lock()
+	if c.cache.Len() == 0 {
+		c.mu.Unlock()
+		return 0
+	}
+	c.cache.RemoveOldest()
+	n := c.cache.Bytes()
+	freed := c.bytes.Swap(n) - n
+	c.items.Store(int64(c.cache.Len()))
+	c.mu.Unlock()
+	c.evictions.Add(1)
+	c.acc.charge(-freed)
+	return freed
+}
+
 // Add 向缓存中添加数据 !!!
 func (c *ConcurrentCache) Add(key string, value common.Value) {
 	c.mu.Lock()
-	defer c.mu.Unlock()
+	defer c.unlockAndCharge()
 	c.cache.Add(key, value)
 
 	// // 延迟初始化->该对象的创建将会延迟至第一次使用该对象时
@@ -82,10 +204,37 @@ func (c *ConcurrentCache) Add(key string, value common.Value) {
 
 }
 
+// AddWithExpire 向缓存中添加一个在 expire 时刻过期的数据
+func (c *ConcurrentCache) AddWithExpire(key string, value common.Value, expire time.Time) {
+	c.mu.Lock()
+	defer c.unlockAndCharge()
+	c.cache.AddWithExpire(key, value, expire)
+}
+
 func (c *ConcurrentCache) Get(key string) (common.Value, bool) {
 	c.mu.Lock()
-	defer c.mu.Unlock()
-	return c.cache.Get(key)
+	defer c.unlockAndCharge()
+	v, ok := c.cache.Get(key)
+	if ok {
+		c.hits.Add(1)
+	} else {
+		c.misses.Add(1)
+	}
+	return v, ok
+}
+
+// Remove 从缓存中删除数据，返回该 key 是否存在
+func (c *ConcurrentCache) Remove(key string) bool {
+	c.mu.Lock()
+	defer c.unlockAndCharge()
+	return c.cache.Remove(key)
+}
+
+// RemoveExpired 清理已过期的数据，返回清理的条目数
+func (c *ConcurrentCache) RemoveExpired() int {
+	c.mu.Lock()
+	defer c.unlockAndCharge()
+	return c.cache.RemoveExpired()
 }
 
 // Add 向分片缓存中添加数据
@@ -94,6 +243,59 @@ func (s *ShardedCache) Add(key string, value data.ByteView) {
 	shard.Add(key, value)
 }
 
+// AddWithExpire 向分片缓存中添加一个在 expire 时刻过期的数据
+func (s *ShardedCache) AddWithExpire(key string, value data.ByteView, expire time.Time) {
+	shard := s.GetShard(key)
+	shard.AddWithExpire(key, value, expire)
+}
+
+// Remove 从 key 所在的分片中删除数据
+func (s *ShardedCache) Remove(key string) bool {
+	shard := s.GetShard(key)
+	return shard.Remove(key)
+}
+
+// RemoveExpired 逐个分片清理已过期的数据，返回清理的条目总数
+func (s *ShardedCache) RemoveExpired() int {
+	removed := 0
+	for _, shard := range s.Shards {
+		removed += shard.RemoveExpired()
+	}
+	return removed
+}
+
+// StartJanitor 启动后台清理协程，每隔 interval 清理一次过期数据；重复调用不会启动多个协程
+func (s *ShardedCache) StartJanitor(interval time.Duration) {
+	s.janitorMu.Lock()
+	defer s.janitorMu.Unlock()
+	if s.stop != nil {
+		return
+	}
+	s.stop = make(chan struct{})
+	go func(stop chan struct{}) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.RemoveExpired()
+			case <-stop:
+				return
+			}
+		}
+	}(s.stop)
+}
+
+// StopJanitor 停止后台清理协程
+func (s *ShardedCache) StopJanitor() {
+	s.janitorMu.Lock()
+	defer s.janitorMu.Unlock()
+	if s.stop != nil {
+		close(s.stop)
+		s.stop = nil
+	}
+}
+
 func (s *ShardedCache) Get(key string) (common.Value, bool) {
 	shard := s.GetShard(key)
 	return shard.Get(key)
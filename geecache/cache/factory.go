@@ -1,12 +1,97 @@
 package cache
 
+import (
+	"GeeCache/geecache/common"
+	"GeeCache/geecache/interfaces"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// PolicyFactory 创建一个淘汰策略。maxBytes 为容量，0 表示不限制；onEvicted 在条目被删除时调用，可为 nil。
+// 只有 NewPolicy 和 NewCache 会传入调用方给出的容量。
+//
+// ConcurrentCache 和 ShardedCache（以及 core.Group）总是以 maxBytes 为 0 创建策略，容量由 Accountant 限制：
+// 超出预算时 Accountant 调用 RemoveOldest，每次调用应淘汰恰好一个条目，
+// Bytes 应返回所有条目的 key 与 value 的长度之和。这样分片之间、Group 之间可以互相借用预算，
+// 所有因容量产生的淘汰也都计入统计。只实现 interfaces.EvictionPolicy 的策略因此看不到预算；
+// 需要按容量划分内部区域的策略应同时实现 interfaces.CapacitySetter，ConcurrentCache 创建策略后
+// 通过 SetCapacity 传入分片的预算份额。
+// 策略同时实现 interfaces.PolicyInspector 时，ConcurrentCache 的 Peek、Contains、Keys 和 Range 才能使用。
+type PolicyFactory func(maxBytes int64, onEvicted func(key string, value common.Value)) interfaces.EvictionPolicy
+
+var (
+	policiesMu sync.RWMutex
+	policies   = make(map[string]PolicyFactory)
+)
+
+func init() {
+	RegisterPolicy("lru", func(maxBytes int64, onEvicted func(string, common.Value)) interfaces.EvictionPolicy {
+		return NewLRUCache(maxBytes, onEvicted)
+	})
+	RegisterPolicy("lfu", func(maxBytes int64, onEvicted func(string, common.Value)) interfaces.EvictionPolicy {
+		return NewLFUCache(maxBytes, onEvicted)
+	})
+	RegisterPolicy("wtinylfu", func(maxBytes int64, onEvicted func(string, common.Value)) interfaces.EvictionPolicy {
+		return NewWTinyLFUCache(maxBytes, onEvicted)
+	})
+	RegisterPolicy("arc", func(maxBytes int64, onEvicted func(string, common.Value)) interfaces.EvictionPolicy {
+		return NewARCCache(maxBytes, onEvicted)
+	})
+	RegisterPolicy("2q", func(maxBytes int64, onEvicted func(string, common.Value)) interfaces.EvictionPolicy {
+		return NewTwoQueueCache(maxBytes, onEvicted)
+	})
+}
+
+// RegisterPolicy 以 name 注册淘汰策略，之后可以在 NewPolicy、NewConcurrentCache 和 core.WithPolicy 中按名字使用。
+// 通过 NewConcurrentCache、ShardedCache 或 core.Group 使用时 factory 收到的 maxBytes 为 0，
+// 分片的预算份额通过 interfaces.CapacitySetter 传入，见 PolicyFactory。
+// 通常在 init 中调用。name 为空、factory 为 nil 或者 name 已被注册时 panic。
+func RegisterPolicy(name string, factory PolicyFactory) {
+	if name == "" {
+		panic("cache: RegisterPolicy with an empty name")
+	}
+	if factory == nil {
+		panic("cache: RegisterPolicy factory is nil for " + name)
+	}
+	policiesMu.Lock()
+	defer policiesMu.Unlock()
+	if _, dup := policies[name]; dup {
+		panic("cache: RegisterPolicy called twice for " + name)
+	}
+	policies[name] = factory
+}
+
+// Policies 返回所有已注册的淘汰策略的名字，按字母顺序排列
+func Policies() []string {
+	policiesMu.RLock()
+	defer policiesMu.RUnlock()
+	names := make([]string, 0, len(policies))
+	for name := range policies {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// NewCache 按算法名创建一个容量为 cacheBytes 的缓存，算法未注册时返回 nil
 func NewCache(algorithm string, cacheBytes int64) Cache {
-	switch algorithm {
-	case "lru":
-		return NewLRUCache(cacheBytes, nil) // 你需要实现此函数
-	case "lfu":
-		return NewLFUCache(int(cacheBytes)) // 你需要实现此函数
-	default:
+	p, err := NewPolicy(algorithm, cacheBytes, nil)
+	if err != nil {
 		return nil
 	}
+	return p
+}
+
+// NewPolicy 按算法名创建淘汰策略，算法未注册时返回错误。
+// onEvicted 在条目被删除时调用，可为 nil。
+func NewPolicy(algorithm string, cacheBytes int64, onEvicted func(string, common.Value)) (interfaces.EvictionPolicy, error) {
+	policiesMu.RLock()
+	factory, ok := policies[algorithm]
+	policiesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unsupported cache algorithm: %q (registered: %s)", algorithm, strings.Join(Policies(), ", "))
+	}
+	return factory(cacheBytes, onEvicted), nil
 }
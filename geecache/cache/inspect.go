@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"GeeCache/geecache/common"
+	"GeeCache/geecache/interfaces"
+)
+
+// 内置的淘汰策略和并发缓存都支持查看和遍历
+var (
+	_ interfaces.PolicyInspector = (*ConcurrentCache)(nil)
+	_ interfaces.PolicyInspector = (*ShardedCache)(nil)
+	_ interfaces.PolicyInspector = (*LRUCache)(nil)
+	_ interfaces.PolicyInspector = (*LFUCache)(nil)
+	_ interfaces.PolicyInspector = (*WTinyLFUCache)(nil)
+	_ interfaces.PolicyInspector = (*ARCCache)(nil)
+	_ interfaces.PolicyInspector = (*TwoQueueCache)(nil)
+)
+
+// inspector 返回淘汰策略的 PolicyInspector 实现，策略没有实现时返回 nil
+func (c *ConcurrentCache) inspector() interfaces.PolicyInspector {
+	i, _ := c.cache.(interfaces.PolicyInspector)
+	return i
+}
+
+// Peek 返回 key 对应的值，不影响淘汰顺序，也不计入命中统计。
+// 淘汰策略没有实现 interfaces.PolicyInspector 时总是返回 false。
+func (c *ConcurrentCache) Peek(key string) (common.Value, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if i := c.inspector(); i != nil {
+		return i.Peek(key)
+	}
+	return nil, false
+}
+
+// Contains 返回 key 是否在缓存中，不影响淘汰顺序，也不计入命中统计
+func (c *ConcurrentCache) Contains(key string) bool {
+	_, ok := c.Peek(key)
+	return ok
+}
+
+// Keys 返回所有未过期的 key
+func (c *ConcurrentCache) Keys() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if i := c.inspector(); i != nil {
+		return i.Keys()
+	}
+	return nil
+}
+
+// Range 对每个未过期的条目调用 f，f 返回 false 时停止。
+// 条目先在锁内复制出来，f 在锁外调用，因此 f 中可以读写缓存，但看到的是调用 Range 时的快照。
+// 淘汰策略没有实现 interfaces.PolicyInspector 时不调用 f。
+func (c *ConcurrentCache) Range(f func(key string, value common.Value) bool) {
+	c.rangeSnapshot(f)
+}
+
+// rangeSnapshot 实现 Range，返回是否遍历了所有条目
+func (c *ConcurrentCache) rangeSnapshot(f func(key string, value common.Value) bool) bool {
+	type kv struct {
+		key   string
+		value common.Value
+	}
+	c.mu.Lock()
+	var entries []kv
+	if i := c.inspector(); i != nil {
+		entries = make([]kv, 0, c.cache.Len())
+		i.Range(func(key string, value common.Value) bool {
+			entries = append(entries, kv{key, value})
+			return true
+		})
+	}
+	c.mu.Unlock()
+	for _, e := range entries {
+		if !f(e.key, e.value) {
+			return false
+		}
+	}
+	return true
+}
+
+// Peek 从 key 所在的分片中查看数据，不影响淘汰顺序，也不计入命中统计
+func (s *ShardedCache) Peek(key string) (common.Value, bool) {
+	return s.GetShard(key).Peek(key)
+}
+
+// Contains 返回 key 是否在缓存中，不影响淘汰顺序，也不计入命中统计
+func (s *ShardedCache) Contains(key string) bool {
+	return s.GetShard(key).Contains(key)
+}
+
+// Keys 返回所有分片中未过期的 key
+func (s *ShardedCache) Keys() []string {
+	var keys []string
+	for _, shard := range s.Shards {
+		keys = append(keys, shard.Keys()...)
+	}
+	return keys
+}
+
+// Range 逐个分片遍历未过期的条目，f 返回 false 时停止。每个分片是单独的快照，见 ConcurrentCache.Range。
+func (s *ShardedCache) Range(f func(key string, value common.Value) bool) {
+	for _, shard := range s.Shards {
+		if !shard.rangeSnapshot(f) {
+			return
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package cache
+
+import (
+	"GeeCache/geecache/common"
+	"GeeCache/geecache/data"
+	"GeeCache/geecache/interfaces"
+	"fmt"
+	"math/rand"
+	"slices"
+	"testing"
+	"time"
+)
+
+// Peek、Contains、Keys 和 Range 不影响淘汰顺序：穿插这些调用后，淘汰的结果与不调用时相同
+func TestInspectDoesNotPromote(t *testing.T) {
+	for _, algorithm := range []string{"lru", "lfu", "wtinylfu", "arc", "2q"} {
+		plain, _ := NewPolicy(algorithm, 256, nil)
+		inspected, _ := NewPolicy(algorithm, 256, nil)
+		inspector := inspected.(interfaces.PolicyInspector)
+		r := rand.New(rand.NewSource(1))
+		for i := 0; i < 2000; i++ {
+			key := fmt.Sprintf("key%d", r.Intn(60))
+			if r.Intn(2) == 0 {
+				plain.Get(key)
+				inspected.Get(key)
+			} else {
+				plain.Add(key, String("value"))
+				inspected.Add(key, String("value"))
+			}
+			peek := fmt.Sprintf("key%d", r.Intn(60))
+			inspector.Peek(peek)
+			inspector.Contains(peek)
+			inspector.Range(func(string, common.Value) bool { return true })
+		}
+		want := plain.(interfaces.PolicyInspector).Keys()
+		if got := inspector.Keys(); !slices.Equal(got, want) {
+			t.Errorf("%s: inspecting changed the cache contents:\n got %v\nwant %v", algorithm, got, want)
+		}
+	}
+}
+
+// 已过期的条目对 Peek 和 Range 不可见
+func TestInspectSkipsExpired(t *testing.T) {
+	lru := NewLRUCache(0, nil)
+	lru.AddWithExpire("old", String("1"), time.Now().Add(-time.Second))
+	lru.Add("new", String("2"))
+	if lru.Contains("old") {
+		t.Fatal("expected an expired entry to be invisible to Contains")
+	}
+	if v, ok := lru.Peek("new"); !ok || v.(String) != "2" {
+		t.Fatalf("Peek(new) = %v, %v", v, ok)
+	}
+	if keys := lru.Keys(); !slices.Equal(keys, []string{"new"}) {
+		t.Fatalf("expected only the live key, got %v", keys)
+	}
+	if lru.Len() != 2 {
+		t.Fatal("inspecting must not remove expired entries")
+	}
+}
+
+// ShardedCache 的查看不计入命中统计，Range 可以提前停止，回调中可以读写缓存
+func TestShardedCacheInspect(t *testing.T) {
+	s := NewShardedCache(4, 0, "lru")
+	for i := 0; i < 100; i++ {
+		s.Add(fmt.Sprintf("key%d", i), data.ByteView{B: []byte("v")})
+	}
+	if !s.Contains("key7") || s.Contains("missing") {
+		t.Fatal("Contains returned a wrong result")
+	}
+	if v, ok := s.Peek("key7"); !ok || v.(data.ByteView).String() != "v" {
+		t.Fatalf("Peek(key7) = %v, %v", v, ok)
+	}
+	if stats := s.Stats(); stats.Hits != 0 || stats.Misses != 0 {
+		t.Fatalf("inspecting should not count as hits or misses: %+v", stats)
+	}
+	if keys := s.Keys(); len(keys) != 100 {
+		t.Fatalf("expected 100 keys, got %d", len(keys))
+	}
+
+	seen := 0
+	s.Range(func(key string, _ common.Value) bool {
+		s.Remove(key)
+		seen++
+		return seen < 10
+	})
+	if seen != 10 || s.Stats().Items != 90 {
+		t.Fatalf("expected Range to stop after 10 entries and remove them, saw %d, %d left", seen, s.Stats().Items)
+	}
+}
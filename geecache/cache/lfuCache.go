@@ -4,165 +4,204 @@ import (
 	"GeeCache/geecache/common"
 	"GeeCache/geecache/data"
 	"container/list"
-	"fmt"
-	"log"
+	"time"
 )
 
 // LFUCache 是一个 LFU 缓存，非并发安全。
+// 所有操作都是 O(1)：频率相同的条目放在同一个频率节点的链表中，频率节点按频率从小到大串成链表，
+// 每个条目通过 data.Entry.Elem 记录自己在链表中的位置。同一频率的条目按 LRU 顺序淘汰。
 type LFUCache struct {
-	maxBytes int                    // 最大容量
-	nbytes   int                    // 当前缓存大小
-	minFreq  int                    // 最小频率
-	freqMap  map[int]*list.List     // 频率到条目列表的映射
+	maxBytes int64                  // 最大容量，0 表示不限制
+	nbytes   int64                  // 当前缓存大小
+	freqs    *list.List             // 频率节点链表，按频率递增；每个节点的值是该频率的条目链表，Front 为最久未访问的条目
+	freqMap  map[int]*list.Element  // 频率到频率节点的映射
 	cache    map[string]*data.Entry // 键到条目的映射
+
+	// 某条记录被移除时的回调函数，可为 nil
+	OnEvicted func(key string, value common.Value)
 }
 
 // NewLFUCache 创建一个 LFU 缓存实例
-func NewLFUCache(maxBytes int) *LFUCache {
+func NewLFUCache(maxBytes int64, onEvicted func(string, common.Value)) *LFUCache {
 	return &LFUCache{
-		maxBytes: maxBytes,
-		nbytes:   0, // 确保 nbytes 从 0 开始
-		freqMap:  make(map[int]*list.List),
-		cache:    make(map[string]*data.Entry),
+		maxBytes:  maxBytes,
+		freqs:     list.New(),
+		freqMap:   make(map[int]*list.Element),
+		cache:     make(map[string]*data.Entry),
+		OnEvicted: onEvicted,
 	}
 }
 
 // Get 获取缓存条目
 func (c *LFUCache) Get(key string) (value common.Value, ok bool) {
 	if entry, ok := c.cache[key]; ok {
+		// 已过期的条目视为未命中，并惰性删除
+		if expired(entry.Expire, time.Now()) {
+			c.removeEntry(entry)
+			return nil, false
+		}
 		// 调用增频函数
 		c.incrementFrequency(entry)
-		log.Printf("Cache hit for key: %s, frequency: %d", key, entry.Frequency)
 		return entry.Value, true
 	}
-	log.Printf("Cache miss for key: %s", key)
 	return nil, false
 }
 
 // Add 添加或更新缓存条目
 func (c *LFUCache) Add(key string, value common.Value) {
-	fmt.Printf("Adding key: %s with value: %v, current nbytes: %d\n", key, value, c.nbytes)
+	c.AddWithExpire(key, value, time.Time{})
+}
+
+// AddWithExpire 添加或更新一个在 expire 时刻过期的条目，expire 为零值表示永不过期
+func (c *LFUCache) AddWithExpire(key string, value common.Value, expire time.Time) {
 	if entry, ok := c.cache[key]; ok {
 		// 如果缓存中已存在，更新条目的值并增加频率
+		c.nbytes += int64(value.Len()) - int64(entry.Value.Len()) // new-old
 		entry.Value = value
+		entry.Expire = expire
 		c.incrementFrequency(entry) // 更新频率
-		c.nbytes += value.Len() - entry.Value.Len()
-		log.Printf("Updated key: %s, new frequency: %d", key, entry.Frequency)
 	} else {
-		// 新增条目
-		if c.nbytes+value.Len() > c.maxBytes {
-			fmt.Println(c.nbytes)
-			c.RemoveOldest() // 超出容量时移除最旧条目
+		// 先为新条目腾出空间，避免新条目因频率最低被立即淘汰
+		size := int64(len(key)) + int64(value.Len())
+		for c.maxBytes != 0 && c.nbytes+size > c.maxBytes && len(c.cache) > 0 {
+			c.RemoveOldest()
 		}
-		newEntry := &data.Entry{
+		entry := &data.Entry{
 			Key:       key,
 			Value:     value,
 			Frequency: 1, // 新条目的频率为 1
+			Expire:    expire,
 		}
-		c.cache[key] = newEntry // 将新条目添加到 cache
-		if _, ok := c.freqMap[1]; !ok {
-			c.freqMap[1] = list.New() // 创建频率 1 的链表
-		}
-		c.freqMap[1].PushBack(newEntry)    // 将条目添加到频率 1 的链表中
-		c.minFreq = 1                      // 确保最小频率为 1
-		c.nbytes += len(key) + value.Len() // 更新缓存的大小
-		log.Printf("Added new key: %s, frequency: %d", key, newEntry.Frequency)
+		c.cache[key] = entry
+		c.pushEntry(entry, nil)
+		c.nbytes += size // 更新缓存的大小
+	}
+	// 更新后的值变大或者单个条目超过容量时，继续淘汰直到回到容量以内
+	for c.maxBytes != 0 && c.maxBytes < c.nbytes {
+		c.RemoveOldest()
 	}
 }
 
-// RemoveOldest 移除频率最低的条目
+// RemoveOldest 移除频率最低的条目，频率相同时移除最久未访问的条目
 func (c *LFUCache) RemoveOldest() {
-	if freqList, ok := c.freqMap[c.minFreq]; ok && freqList.Len() > 0 {
-		oldest := freqList.Front()
-		if oldest != nil {
-			entry := oldest.Value.(*data.Entry)
-			// 从缓存中移除条目
-			delete(c.cache, entry.Key)
-			// 从频率列表中移除条目
-			freqList.Remove(oldest)
-			c.nbytes -= len(entry.Key) + entry.Value.Len()
-			// 如果频率列表为空，删除频率列表并更新最小频率
-			if freqList.Len() == 0 {
-				delete(c.freqMap, c.minFreq)
-				// 更新 minFreq
-				for newMinFreq := c.minFreq + 1; newMinFreq <= len(c.freqMap); newMinFreq++ {
-					if _, exists := c.freqMap[newMinFreq]; exists {
-						c.minFreq = newMinFreq
-						break
-					}
-				}
-			}
-			log.Printf("Removed oldest key: %s, frequency: %d", entry.Key, c.minFreq)
+	if front := c.freqs.Front(); front != nil {
+		oldest := front.Value.(*list.List).Front()
+		c.removeEntry(oldest.Value.(*data.Entry))
+	}
+}
+
+// Bytes 返回当前占用的字节数
+func (c *LFUCache) Bytes() int64 {
+	return c.nbytes
+}
+
+// Remove 删除指定 key 的条目，返回该 key 是否存在
+func (c *LFUCache) Remove(key string) bool {
+	if entry, ok := c.cache[key]; ok {
+		c.removeEntry(entry)
+		return true
+	}
+	return false
+}
+
+// RemoveExpired 清理所有已过期的条目，返回清理的条目数
+func (c *LFUCache) RemoveExpired() int {
+	now := time.Now()
+	removed := 0
+	for _, entry := range c.cache {
+		if expired(entry.Expire, now) {
+			c.removeEntry(entry)
+			removed++
 		}
 	}
+	return removed
 }
 
-// incrementFrequency 增加条目的访问频率
+// removeEntry 从缓存和频率链表中删除指定条目，并触发 OnEvicted 回调
+func (c *LFUCache) removeEntry(entry *data.Entry) {
+	c.unlink(entry.Frequency, entry.Elem)
+	entry.Elem = nil
+	delete(c.cache, entry.Key)
+	c.nbytes -= int64(len(entry.Key)) + int64(entry.Value.Len())
+	if c.OnEvicted != nil {
+		c.OnEvicted(entry.Key, entry.Value)
+	}
+}
+
+// incrementFrequency 增加条目的访问频率，把条目移到下一个频率节点
 func (c *LFUCache) incrementFrequency(entry *data.Entry) {
-	// 获取当前频率列表
-	freqList := c.freqMap[entry.Frequency]
-	// 从当前频率列表中移除条目
-	for elem := freqList.Front(); elem != nil; elem = elem.Next() {
-		if elem.Value.(*data.Entry) == entry {
-			freqList.Remove(elem)
-			break
+	// 先加入新的频率节点再从旧节点删除，旧节点被删除之前可以作为新节点的插入位置
+	node, elem := c.freqMap[entry.Frequency], entry.Elem
+	entry.Frequency++
+	c.pushEntry(entry, node)
+	c.unlink(entry.Frequency-1, elem)
+}
+
+// pushEntry 把条目加入频率为 entry.Frequency 的节点末尾。
+// 节点不存在时创建在 prev 之后，prev 为 nil 时创建在最前面。
+func (c *LFUCache) pushEntry(entry *data.Entry, prev *list.Element) {
+	node, ok := c.freqMap[entry.Frequency]
+	if !ok {
+		if prev == nil {
+			node = c.freqs.PushFront(list.New())
+		} else {
+			node = c.freqs.InsertAfter(list.New(), prev)
 		}
+		c.freqMap[entry.Frequency] = node
 	}
-	// 如果当前频率列表为空，删除频率列表并更新最小频率
-	if freqList.Len() == 0 {
-		delete(c.freqMap, entry.Frequency)
-		// 如果最小频率被移除，更新 minFreq
-		if c.minFreq == entry.Frequency {
-			c.minFreq++
-		}
+	entry.Elem = node.Value.(*list.List).PushBack(entry)
+}
+
+// unlink 从频率为 freq 的节点中删除 elem，节点为空时删除该节点
+func (c *LFUCache) unlink(freq int, elem *list.Element) {
+	node := c.freqMap[freq]
+	items := node.Value.(*list.List)
+	items.Remove(elem)
+	if items.Len() == 0 {
+		c.freqs.Remove(node)
+		delete(c.freqMap, freq)
 	}
+}
 
-	// 增加频率并添加到新的频率列表
-	entry.Frequency++
-	if _, ok := c.freqMap[entry.Frequency]; !ok {
-		c.freqMap[entry.Frequency] = list.New()
+// Peek 返回 key 对应的值，不增加访问频率
+func (c *LFUCache) Peek(key string) (value common.Value, ok bool) {
+	if entry, ok := c.cache[key]; ok && !expired(entry.Expire, time.Now()) {
+		return entry.Value, true
+	}
+	return nil, false
+}
+
+// Contains 返回 key 是否在缓存中，不增加访问频率
+func (c *LFUCache) Contains(key string) bool {
+	_, ok := c.Peek(key)
+	return ok
+}
+
+// Keys 返回所有 key，从访问频率最高到最低
+func (c *LFUCache) Keys() []string {
+	keys := make([]string, 0, len(c.cache))
+	c.Range(func(key string, _ common.Value) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return keys
+}
+
+// Range 从访问频率最高到最低遍历条目，频率相同时从最近访问的条目开始，f 返回 false 时停止
+func (c *LFUCache) Range(f func(key string, value common.Value) bool) {
+	now := time.Now()
+	for node := c.freqs.Back(); node != nil; node = node.Prev() {
+		for ele := node.Value.(*list.List).Back(); ele != nil; ele = ele.Prev() {
+			entry := ele.Value.(*data.Entry)
+			if expired(entry.Expire, now) {
+				continue
+			}
+			if !f(entry.Key, entry.Value) {
+				return
+			}
+		}
 	}
-	c.freqMap[entry.Frequency].PushBack(entry)
-}
-
-// // addEntry 添加条目到频率列表
-// func (c *LFUCache) addEntry(e *data.Entry) {
-// 	if _, ok := c.freqMap[e.Frequency]; !ok {
-// 		c.freqMap[e.Frequency] = list.New()
-// 	}
-// 	c.freqMap[e.Frequency].PushFront(e)
-// 	if e.Frequency == 1 || e.Frequency < c.minFreq {
-// 		c.minFreq = e.Frequency
-// 	}
-// }
-// // RemoveOldest 移除频率最低的条目
-// func (c *LFUCache) RemoveOldest() {
-// 	if freqList, ok := c.freqMap[c.minFreq]; ok {
-// 		oldest := freqList.Front()
-// 		if oldest != nil {
-// 			entry := oldest.Value.(*data.Entry)
-// 			delete(c.cache, entry.Key)
-// 			freqList.Remove(oldest)
-// 			c.nbytes -= len(entry.Key) + len(entry.Value.B)
-// 			if freqList.Len() == 0 {
-// 				delete(c.freqMap, c.minFreq)
-// 			}
-// 		}
-// 	}
-// }
-
-// // removeLeastFrequent 移除最少使用的条目
-// func (c *LFUCache) removeLeastFrequent() {
-// 	if freqList, ok := c.freqMap[c.minFreq]; ok {
-// 		leastElem := freqList.Back()
-// 		if leastElem != nil {
-// 			leastEntry := leastElem.Value.(*data.Entry)
-// 			freqList.Remove(leastElem)
-// 			delete(c.cache, leastEntry.Key)
-// 			c.nbytes--
-// 		}
-// 	}
-// }
+}
 
 // Len 返回缓存条目数
 func (c *LFUCache) Len() int {
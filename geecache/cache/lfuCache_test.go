@@ -2,8 +2,11 @@ package cache_test
 
 import (
 	"GeeCache/geecache/cache"
+	"GeeCache/geecache/common"
 	"fmt"
+	"slices"
 	"testing"
+	"time"
 )
 
 // 自定义类型，作为缓存条目的类型
@@ -19,7 +22,7 @@ func TestLFUCacheWithCustomValue(t *testing.T) {
 	// k1, k2, k3 := "key1", "key2", "key3"
 	// v1, v2, v3 := "value1", "value2", "value3"
 	// 创建一个 LFU 缓存，最大容量为 10 字节
-	lfuCache := cache.NewLFUCache(24)
+	lfuCache := cache.NewLFUCache(24, nil)
 
 	// 使用自定义类型 MyValue 添加条目
 	lfuCache.Add("key1", MyValue{data: "val1"})
@@ -47,3 +50,69 @@ func TestLFUCacheWithCustomValue(t *testing.T) {
 		t.Errorf("expected value of key4 to be 'val4', but got %v", value)
 	}
 }
+
+func TestLFUCacheExpire(t *testing.T) {
+	lfuCache := cache.NewLFUCache(1024, nil)
+	lfuCache.AddWithExpire("key1", MyValue{data: "val1"}, time.Now().Add(-time.Second))
+	lfuCache.AddWithExpire("key2", MyValue{data: "val2"}, time.Now().Add(time.Hour))
+	lfuCache.AddWithExpire("key3", MyValue{data: "val3"}, time.Now().Add(-time.Second))
+
+	if _, ok := lfuCache.Get("key1"); ok {
+		t.Errorf("expected key1 to be expired")
+	}
+	if n := lfuCache.RemoveExpired(); n != 1 || lfuCache.Len() != 1 {
+		t.Errorf("expected RemoveExpired to leave 1 entry, removed %d, left %d", n, lfuCache.Len())
+	}
+	if _, ok := lfuCache.Get("key2"); !ok {
+		t.Errorf("expected key2 to be alive")
+	}
+}
+
+func TestLFUCacheEvictsLeastFrequent(t *testing.T) {
+	var evicted []string
+	lfuCache := cache.NewLFUCache(24, func(key string, _ common.Value) {
+		evicted = append(evicted, key)
+	})
+	lfuCache.Add("key1", MyValue{data: "val1"})
+	lfuCache.Add("key2", MyValue{data: "val2"})
+	lfuCache.Add("key3", MyValue{data: "val3"})
+	// key1 访问 2 次，key3 访问 1 次，key2 频率最低
+	lfuCache.Get("key1")
+	lfuCache.Get("key1")
+	lfuCache.Get("key3")
+
+	lfuCache.Add("key4", MyValue{data: "val4"})
+	// key4 频率为 1，key3 频率为 2，因此 key4 先于 key3 被淘汰
+	lfuCache.Add("key5", MyValue{data: "val5"})
+	if want := []string{"key2", "key4"}; !slices.Equal(evicted, want) {
+		t.Fatalf("expected evictions %v, got %v", want, evicted)
+	}
+	if lfuCache.Bytes() != 24 || lfuCache.Len() != 3 {
+		t.Fatalf("expected 3 entries in 24 bytes, got %d in %d", lfuCache.Len(), lfuCache.Bytes())
+	}
+	if lfuCache.Remove("key1"); len(evicted) != 3 || evicted[2] != "key1" {
+		t.Fatalf("expected Remove to call OnEvicted, got %v", evicted)
+	}
+}
+
+func TestLFUCacheUpdateBytes(t *testing.T) {
+	lfuCache := cache.NewLFUCache(20, nil)
+	lfuCache.Add("key1", MyValue{data: "val1"})
+	lfuCache.Add("key2", MyValue{data: "val2"})
+	lfuCache.Get("key2")
+
+	// 更新后 key1 占 4+10 字节，总量超过容量；两个条目的频率都为 2，淘汰更久未访问的 key2
+	lfuCache.Add("key1", MyValue{data: "0123456789"})
+	if lfuCache.Bytes() != 14 || lfuCache.Len() != 1 {
+		t.Fatalf("expected only key1 in 14 bytes, got %d entries in %d bytes", lfuCache.Len(), lfuCache.Bytes())
+	}
+	if _, ok := lfuCache.Get("key1"); !ok {
+		t.Fatal("expected key1 to stay after update")
+	}
+
+	// 单个条目超过容量时不会留在缓存中
+	lfuCache.Add("huge", MyValue{data: "0123456789012345678901234"})
+	if lfuCache.Bytes() != 0 || lfuCache.Len() != 0 {
+		t.Fatalf("expected an oversized entry to be evicted, got %d entries in %d bytes", lfuCache.Len(), lfuCache.Bytes())
+	}
+}
@@ -3,6 +3,7 @@ package cache
 import (
 	"GeeCache/geecache/common"
 	"container/list"
+	"time"
 )
 
 // var _ cache.EvictionPolicy = (*Cache)(nil)
@@ -21,8 +22,9 @@ type LRUCache struct {
 
 // 双向链表节点的数据类型
 type entry struct {
-	key   string // 在链表中仍保存每个值对应的 key 的好处:淘汰队首节点时，需要用 key 从字典中删除对应的映射。
-	value common.Value
+	key    string // 在链表中仍保存每个值对应的 key 的好处:淘汰队首节点时，需要用 key 从字典中删除对应的映射。
+	value  common.Value
+	expire time.Time // 过期时间，零值表示永不过期
 }
 
 // // Value use Len to count how many bytes it takes
@@ -49,6 +51,11 @@ func (c *LRUCache) Get(key string) (value common.Value, ok bool) {
 		// (*entry) 是一个类型断言，用于将 ele.Value 转换为具体的 *entry 类型。
 		// 如果 ele.Value 实际上是 *entry 类型的值，类型断言将成功，并返回这个值；否则，程序会发生运行时错误（panic）。
 		kv := ele.Value.(*entry)
+		// 已过期的条目对调用方不可见，顺便惰性回收
+		if expired(kv.expire, time.Now()) {
+			c.removeElement(ele)
+			return nil, false
+		}
 		return kv.value, true
 	}
 	return
@@ -58,27 +65,62 @@ func (c *LRUCache) Get(key string) (value common.Value, ok bool) {
 func (c *LRUCache) RemoveOldest() {
 	ele := c.ll.Back() // 取到队首节点，从链表中删除
 	if ele != nil {
-		c.ll.Remove(ele)
-		kv := ele.Value.(*entry)
-		delete(c.Cache, kv.key)                                // 从字典 c.Cache 中删除该节点的映射关系
-		c.nbytes -= int64(len(kv.key)) + int64(kv.value.Len()) // 更新当前所用的内存 c.nbytes
-		// 回调函数 OnEvicted 不为 nil，则调用回调函数
-		if c.OnEvicted != nil {
-			c.OnEvicted(kv.key, kv.value)
+		c.removeElement(ele)
+	}
+}
+
+// Remove 删除指定 key 的条目，返回该 key 是否存在
+func (c *LRUCache) Remove(key string) bool {
+	if ele, ok := c.Cache[key]; ok {
+		c.removeElement(ele)
+		return true
+	}
+	return false
+}
+
+// RemoveExpired 清理所有已过期的条目，返回清理的条目数
+func (c *LRUCache) RemoveExpired() int {
+	now := time.Now()
+	removed := 0
+	for ele := c.ll.Back(); ele != nil; {
+		prev := ele.Prev()
+		if expired(ele.Value.(*entry).expire, now) {
+			c.removeElement(ele)
+			removed++
 		}
+		ele = prev
+	}
+	return removed
+}
+
+// removeElement 从链表和字典中删除节点，并触发 OnEvicted 回调
+func (c *LRUCache) removeElement(ele *list.Element) {
+	c.ll.Remove(ele)
+	kv := ele.Value.(*entry)
+	delete(c.Cache, kv.key)                                // 从字典 c.Cache 中删除该节点的映射关系
+	c.nbytes -= int64(len(kv.key)) + int64(kv.value.Len()) // 更新当前所用的内存 c.nbytes
+	// 回调函数 OnEvicted 不为 nil，则调用回调函数
+	if c.OnEvicted != nil {
+		c.OnEvicted(kv.key, kv.value)
 	}
 }
 
 // 3.Add adds a value to the Cache.
 func (c *LRUCache) Add(key string, value common.Value) {
+	c.AddWithExpire(key, value, time.Time{})
+}
+
+// AddWithExpire 添加一个在 expire 时刻过期的条目，expire 为零值表示永不过期
+func (c *LRUCache) AddWithExpire(key string, value common.Value, expire time.Time) {
 	if ele, ok := c.Cache[key]; ok { // 键存在，则更新对应节点的值，并将该节点移到队尾。
 		c.ll.MoveToFront(ele)
 		kv := ele.Value.(*entry)
 		c.nbytes += int64(value.Len()) - int64(kv.value.Len()) // new-old
 		kv.value = value
+		kv.expire = expire
 	} else { // 不存在则
-		ele := c.ll.PushFront(&entry{key, value}) // 队尾添加新节点 &entry{key, value}
-		c.Cache[key] = ele                        // 字典中添加 key 和节点的映射关系
+		ele := c.ll.PushFront(&entry{key, value, expire}) // 队尾添加新节点 &entry{key, value, expire}
+		c.Cache[key] = ele                                // 字典中添加 key 和节点的映射关系
 		c.nbytes += int64(len(key)) + int64(value.Len())
 	}
 	for c.maxBytes != 0 && c.maxBytes < c.nbytes { // 超过了设定的最大值 c.maxBytes
@@ -86,7 +128,58 @@ func (c *LRUCache) Add(key string, value common.Value) {
 	}
 }
 
+// Bytes 返回当前占用的字节数
+func (c *LRUCache) Bytes() int64 {
+	return c.nbytes
+}
+
 // Len the number of Cache entries
 func (c *LRUCache) Len() int {
 	return c.ll.Len()
 }
+
+// expired 判断过期时间 expire 在 now 时刻是否已经到达，零值表示永不过期
+func expired(expire time.Time, now time.Time) bool {
+	return !expire.IsZero() && !now.Before(expire)
+}
+
+// Peek 返回 key 对应的值，不移动条目的位置
+func (c *LRUCache) Peek(key string) (value common.Value, ok bool) {
+	if ele, ok := c.Cache[key]; ok {
+		kv := ele.Value.(*entry)
+		if !expired(kv.expire, time.Now()) {
+			return kv.value, true
+		}
+	}
+	return nil, false
+}
+
+// Contains 返回 key 是否在缓存中，不移动条目的位置
+func (c *LRUCache) Contains(key string) bool {
+	_, ok := c.Peek(key)
+	return ok
+}
+
+// Keys 返回所有 key，从最近访问到最久未访问
+func (c *LRUCache) Keys() []string {
+	keys := make([]string, 0, c.ll.Len())
+	c.Range(func(key string, _ common.Value) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return keys
+}
+
+// Range 从最近访问到最久未访问遍历条目，f 返回 false 时停止
+func (c *LRUCache) Range(f func(key string, value common.Value) bool) {
+	now := time.Now()
+	for ele := c.ll.Front(); ele != nil; ele = ele.Next() {
+		kv := ele.Value.(*entry)
+		if expired(kv.expire, now) {
+			continue
+		}
+		if !f(kv.key, kv.value) {
+			return
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"GeeCache/geecache/common"
 	"reflect"
 	"testing"
+	"time"
 )
 
 type String string
@@ -59,3 +60,39 @@ func TsetOnEvicted(t *testing.T) {
 		t.Fatalf("Call OnEvicted failed, expect keys equals to %s", expect)
 	}
 }
+
+// 测试过期条目对 Get 不可见，并能被 RemoveExpired 回收
+func TestExpire(t *testing.T) {
+	lru := NewLRUCache(int64(0), nil)
+	lru.AddWithExpire("key1", String("1234"), time.Now().Add(-time.Second))
+	lru.AddWithExpire("key2", String("1234"), time.Now().Add(time.Hour))
+	lru.AddWithExpire("key3", String("1234"), time.Now().Add(-time.Second))
+	lru.Add("key4", String("1234"))
+
+	if _, ok := lru.Get("key1"); ok || lru.Len() != 3 {
+		t.Fatalf("expired key1 should be invisible and reclaimed lazily")
+	}
+	if n := lru.RemoveExpired(); n != 1 || lru.Len() != 2 {
+		t.Fatalf("RemoveExpired removed %d entries, %d left", n, lru.Len())
+	}
+	if _, ok := lru.Get("key2"); !ok {
+		t.Fatalf("key2 should not expire yet")
+	}
+	if _, ok := lru.Get("key4"); !ok {
+		t.Fatalf("key4 should never expire")
+	}
+}
+
+func TestRemove(t *testing.T) {
+	lru := NewLRUCache(int64(0), nil)
+	lru.Add("key1", String("1234"))
+	if !lru.Remove("key1") || lru.Len() != 0 {
+		t.Fatalf("Remove key1 failed")
+	}
+	if _, ok := lru.Get("key1"); ok {
+		t.Fatalf("key1 should be removed")
+	}
+	if lru.Remove("key1") {
+		t.Fatalf("Remove of a missing key should report false")
+	}
+}
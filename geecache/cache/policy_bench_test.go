@@ -0,0 +1,95 @@
+package cache_test
+
+import (
+	"GeeCache/geecache/cache"
+	"GeeCache/geecache/data"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+// zipfTrace 生成服从 Zipf 分布的访问序列，s 越大热点越集中
+func zipfTrace(n int, s float64, keys uint64, seed int64) []string {
+	z := rand.NewZipf(rand.New(rand.NewSource(seed)), s, 1, keys-1)
+	trace := make([]string, n)
+	for i := range trace {
+		trace[i] = "key" + strconv.FormatUint(z.Uint64(), 10)
+	}
+	return trace
+}
+
+// scanTrace 在 Zipf 访问序列中每隔 period 次访问插入一段长度为 length 的一次性扫描
+func scanTrace(n int, s float64, keys uint64, period, length int, seed int64) []string {
+	trace := zipfTrace(n, s, keys, seed)
+	scan := 0
+	for i := period; i+length <= len(trace); i += period {
+		for j := 0; j < length; j++ {
+			trace[i+j] = "scan" + strconv.Itoa(scan)
+			scan++
+		}
+	}
+	return trace
+}
+
+// shiftTrace 把 Zipf 访问序列分成 phases 段，每段的热点换成另一批 key，用于观察算法能否忘记过去的热点
+func shiftTrace(n int, s float64, keys uint64, phases int, seed int64) []string {
+	trace := zipfTrace(n, s, keys, seed)
+	for i := range trace {
+		trace[i] = "p" + strconv.Itoa(i*phases/n) + trace[i]
+	}
+	return trace
+}
+
+// BenchmarkHitRatio 在相同的访问序列上比较各淘汰算法的命中率，命中率通过 hit-ratio 指标报告。
+// 缓存容量约为 key 空间的 1%，未命中时写入缓存。
+// sharded 子项使用与 Group 相同的 ShardedCache：策略本身不限制容量，由 Accountant 淘汰。
+func BenchmarkHitRatio(b *testing.B) {
+	const (
+		keys     = 100000
+		traceLen = 1 << 20
+	)
+	value := data.ByteView{B: make([]byte, 32)}
+	capacity := int64(keys / 100 * (len("p0key00000") + value.Len()))
+	traces := []struct {
+		name  string
+		trace []string
+	}{
+		{"zipf=1.01", zipfTrace(traceLen, 1.01, keys, 1)},
+		{"zipf=1.2", zipfTrace(traceLen, 1.2, keys, 1)},
+		{"zipf=1.01+scan", scanTrace(traceLen, 1.01, keys, 10000, 2000, 1)},
+		{"zipf=1.01+shift", shiftTrace(traceLen, 1.01, keys, 4, 1)},
+	}
+	for _, tr := range traces {
+		for _, algorithm := range []string{"lru", "lfu", "wtinylfu", "arc", "2q"} {
+			b.Run(fmt.Sprintf("%s/%s", tr.name, algorithm), func(b *testing.B) {
+				c := cache.NewCache(algorithm, capacity)
+				hitRatio(b, tr.trace, func(key string) bool {
+					_, ok := c.Get(key)
+					return ok
+				}, func(key string) { c.Add(key, value) })
+			})
+			b.Run(fmt.Sprintf("%s/sharded/%s", tr.name, algorithm), func(b *testing.B) {
+				c := cache.NewShardedCache(16, capacity, algorithm)
+				hitRatio(b, tr.trace, func(key string) bool {
+					_, ok := c.Get(key)
+					return ok
+				}, func(key string) { c.Add(key, value) })
+			})
+		}
+	}
+}
+
+// hitRatio 按 trace 依次读取，未命中时写入，报告命中率
+func hitRatio(b *testing.B, trace []string, get func(key string) bool, add func(key string)) {
+	hits := 0
+	for i := 0; i < b.N; i++ {
+		key := trace[i%len(trace)]
+		if get(key) {
+			hits++
+		} else {
+			add(key)
+		}
+	}
+	b.ReportMetric(float64(hits)/float64(b.N), "hit-ratio")
+}
@@ -0,0 +1,205 @@
+package cache
+
+import (
+	"GeeCache/geecache/common"
+	"GeeCache/geecache/interfaces"
+	"container/list"
+	"time"
+)
+
+// segEntry 是分段缓存中的一个条目，seg 为所在的段
+type segEntry struct {
+	key    string
+	value  common.Value
+	expire time.Time // 过期时间，零值表示永不过期
+	seg    int
+}
+
+func (e *segEntry) size() int64 {
+	return int64(len(e.key)) + int64(e.value.Len())
+}
+
+// segments 把条目分成若干个 LRU 链表（段），并记录每段占用的字节数。
+// W-TinyLFU、ARC 和 2Q 都由它保存实际缓存的数据，只是在段之间移动条目的规则不同。
+type segments struct {
+	lists  []*list.List             // 每段的链表，Front 为最近访问的条目
+	bytes  []int64                  // 每段占用的字节数
+	items  map[string]*list.Element // 键到链表节点的映射
+	nbytes int64                    // 所有段占用的字节数
+	hint   int64                    // SetCapacity 设置的容量，0 表示未设置
+}
+
+func newSegments(n int) segments {
+	s := segments{
+		lists: make([]*list.List, n),
+		bytes: make([]int64, n),
+		items: make(map[string]*list.Element),
+	}
+	for i := range s.lists {
+		s.lists[i] = list.New()
+	}
+	return s
+}
+
+// 按容量划分区域的策略在 Accountant 下通过 SetCapacity 得到分片的份额
+var (
+	_ interfaces.CapacitySetter = (*WTinyLFUCache)(nil)
+	_ interfaces.CapacitySetter = (*ARCCache)(nil)
+	_ interfaces.CapacitySetter = (*TwoQueueCache)(nil)
+)
+
+// SetCapacity 设置不限制容量（maxBytes 为 0）时计算各段和幽灵链表大小使用的容量，
+// 通常是分片在 Accountant 中的预算份额，见 interfaces.CapacitySetter。不会触发淘汰。
+func (s *segments) SetCapacity(bytes int64) {
+	s.hint = max(bytes, 0)
+}
+
+// limit 返回计算各段大小时使用的容量：maxBytes 不为 0 时即为 maxBytes，
+// 否则取 SetCapacity 设置的容量与当前占用的字节数中较大的一个
+func (s *segments) limit(maxBytes int64) int64 {
+	if maxBytes != 0 {
+		return maxBytes
+	}
+	return max(s.hint, s.nbytes)
+}
+
+// push 把新条目加入 seg 段的最前面
+func (s *segments) push(seg int, key string, value common.Value, expire time.Time) {
+	e := &segEntry{key: key, value: value, expire: expire, seg: seg}
+	s.items[key] = s.lists[seg].PushFront(e)
+	s.bytes[seg] += e.size()
+	s.nbytes += e.size()
+}
+
+// update 更新已有条目的值和过期时间，不改变条目的位置
+func (s *segments) update(ele *list.Element, value common.Value, expire time.Time) {
+	e := ele.Value.(*segEntry)
+	delta := int64(value.Len()) - int64(e.value.Len()) // new-old
+	s.bytes[e.seg] += delta
+	s.nbytes += delta
+	e.value = value
+	e.expire = expire
+}
+
+// moveTo 把条目移到 seg 段的最前面，ele 之后不再可用
+func (s *segments) moveTo(ele *list.Element, seg int) {
+	e := ele.Value.(*segEntry)
+	s.lists[e.seg].Remove(ele)
+	s.bytes[e.seg] -= e.size()
+	e.seg = seg
+	s.items[e.key] = s.lists[seg].PushFront(e)
+	s.bytes[seg] += e.size()
+}
+
+// remove 删除条目并返回它
+func (s *segments) remove(ele *list.Element) *segEntry {
+	e := ele.Value.(*segEntry)
+	s.lists[e.seg].Remove(ele)
+	s.bytes[e.seg] -= e.size()
+	s.nbytes -= e.size()
+	delete(s.items, e.key)
+	return e
+}
+
+// expired 返回所有在 now 时刻已过期的条目
+func (s *segments) expired(now time.Time) []*list.Element {
+	var eles []*list.Element
+	for _, ele := range s.items {
+		if expired(ele.Value.(*segEntry).expire, now) {
+			eles = append(eles, ele)
+		}
+	}
+	return eles
+}
+
+// Peek 返回 key 对应的值，不移动条目，也不记录访问
+func (s *segments) Peek(key string) (value common.Value, ok bool) {
+	if ele, ok := s.items[key]; ok {
+		e := ele.Value.(*segEntry)
+		if !expired(e.expire, time.Now()) {
+			return e.value, true
+		}
+	}
+	return nil, false
+}
+
+// Contains 返回 key 是否在缓存中，不移动条目，也不记录访问
+func (s *segments) Contains(key string) bool {
+	_, ok := s.Peek(key)
+	return ok
+}
+
+// Keys 返回所有 key，顺序与 Range 相同
+func (s *segments) Keys() []string {
+	keys := make([]string, 0, len(s.items))
+	s.Range(func(key string, _ common.Value) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return keys
+}
+
+// Range 按段的顺序遍历条目，段内从最近访问的条目开始，f 返回 false 时停止
+func (s *segments) Range(f func(key string, value common.Value) bool) {
+	now := time.Now()
+	for _, l := range s.lists {
+		for ele := l.Front(); ele != nil; ele = ele.Next() {
+			e := ele.Value.(*segEntry)
+			if expired(e.expire, now) {
+				continue
+			}
+			if !f(e.key, e.value) {
+				return
+			}
+		}
+	}
+}
+
+// ghostList 是只记录 key 和条目大小的 LRU 链表，用于记住最近被淘汰的 key。
+// 大小按被淘汰条目原来占用的字节数计算，这样可以和缓存容量放在一起比较。
+type ghostList struct {
+	ll    *list.List // Front 为最近淘汰的 key
+	keys  map[string]*list.Element
+	bytes int64
+}
+
+type ghostEntry struct {
+	key  string
+	size int64
+}
+
+func newGhostList() ghostList {
+	return ghostList{ll: list.New(), keys: make(map[string]*list.Element)}
+}
+
+// push 记录一个被淘汰的条目
+func (g *ghostList) push(key string, size int64) {
+	g.remove(key)
+	g.keys[key] = g.ll.PushFront(&ghostEntry{key: key, size: size})
+	g.bytes += size
+}
+
+// contains 返回 key 是否有记录
+func (g *ghostList) contains(key string) bool {
+	_, ok := g.keys[key]
+	return ok
+}
+
+// remove 删除 key 的记录，返回 key 是否存在
+func (g *ghostList) remove(key string) bool {
+	ele, ok := g.keys[key]
+	if !ok {
+		return false
+	}
+	g.ll.Remove(ele)
+	delete(g.keys, key)
+	g.bytes -= ele.Value.(*ghostEntry).size
+	return true
+}
+
+// trim 删除最早的记录，直到占用不超过 limit 字节
+func (g *ghostList) trim(limit int64) {
+	for g.bytes > max(limit, 0) {
+		g.remove(g.ll.Back().Value.(*ghostEntry).key)
+	}
+}
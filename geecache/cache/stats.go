@@ -0,0 +1,48 @@
+package cache
+
+// Stats 是缓存的统计快照
+type Stats struct {
+	Hits      int64 // Get 命中次数
+	Misses    int64 // Get 未命中次数，已过期的条目也算未命中
+	Evictions int64 // 因超出容量被淘汰的条目数，不包括过期和主动删除
+	Items     int64 // 当前条目数
+	Bytes     int64 // 当前占用的字节数
+}
+
+// add 把 other 累加到 s 上
+func (s *Stats) add(other Stats) {
+	s.Hits += other.Hits
+	s.Misses += other.Misses
+	s.Evictions += other.Evictions
+	s.Items += other.Items
+	s.Bytes += other.Bytes
+}
+
+// Stats 返回分片的统计快照，只读取原子计数器，不加锁
+func (c *ConcurrentCache) Stats() Stats {
+	return Stats{
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Evictions: c.evictions.Load(),
+		Items:     c.items.Load(),
+		Bytes:     c.bytes.Load(),
+	}
+}
+
+// Stats 返回所有分片合计的统计快照
+func (s *ShardedCache) Stats() Stats {
+	var total Stats
+	for _, shard := range s.Shards {
+		total.add(shard.Stats())
+	}
+	return total
+}
+
+// ShardStats 返回每个分片的统计快照，下标与 Shards 相同
+func (s *ShardedCache) ShardStats() []Stats {
+	stats := make([]Stats, len(s.Shards))
+	for i, shard := range s.Shards {
+		stats[i] = shard.Stats()
+	}
+	return stats
+}
@@ -0,0 +1,144 @@
+package cache
+
+import (
+	"GeeCache/geecache/common"
+	"container/list"
+	"time"
+)
+
+// 2Q 的两个段
+const (
+	twoQIn   = iota // A1in：只访问过一次的条目，先进先出
+	twoQMain        // Am：在 A1out 中命中过的条目，LRU
+)
+
+// 2Q 各部分占容量的比例
+const (
+	twoQInPercent  = 25 // A1in 占容量的百分比
+	twoQOutPercent = 50 // A1out 记录的被淘汰条目合计占容量的百分比
+)
+
+// TwoQueueCache 是一个 2Q 缓存，非并发安全。
+//
+// 新条目进入先进先出的 A1in 队列，在其中再次访问不会改变顺序，因此一次性扫描只会占用 A1in。
+// 从 A1in 淘汰的 key 记入幽灵队列 A1out；在 A1out 中的 key 再次写入时说明它会被反复访问，放入 LRU 的 Am。
+// A1out 按字节计算大小，记录条目被淘汰前的大小，与 LRUCache 的 maxBytes 一致。
+//
+// maxBytes 为 0 时不主动淘汰，由调用方（例如 Accountant）调用 RemoveOldest，
+// 此时按 SetCapacity 设置的容量计算，没有设置时按当前占用的字节数作为容量。
+type TwoQueueCache struct {
+	maxBytes int64
+	segments           // A1in 和 Am
+	out      ghostList // A1out
+
+	// 某条记录被移除时的回调函数，可为 nil
+	OnEvicted func(key string, value common.Value)
+}
+
+// NewTwoQueueCache 创建一个 2Q 缓存实例，maxBytes 为 0 表示不限制
+func NewTwoQueueCache(maxBytes int64, onEvicted func(string, common.Value)) *TwoQueueCache {
+	return &TwoQueueCache{
+		maxBytes:  maxBytes,
+		segments:  newSegments(2),
+		out:       newGhostList(),
+		OnEvicted: onEvicted,
+	}
+}
+
+// Get 获取缓存条目，Am 中命中的条目移到最前面，A1in 中的条目不移动
+func (c *TwoQueueCache) Get(key string) (value common.Value, ok bool) {
+	ele, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	e := ele.Value.(*segEntry)
+	// 已过期的条目视为未命中，并惰性删除
+	if expired(e.expire, time.Now()) {
+		c.removeElement(ele)
+		return nil, false
+	}
+	if e.seg == twoQMain {
+		c.lists[twoQMain].MoveToFront(ele)
+	}
+	return e.value, true
+}
+
+// Add 添加或更新缓存条目
+func (c *TwoQueueCache) Add(key string, value common.Value) {
+	c.AddWithExpire(key, value, time.Time{})
+}
+
+// AddWithExpire 添加或更新一个在 expire 时刻过期的条目，expire 为零值表示永不过期。
+// key 在 A1out 中时放入 Am，否则放入 A1in。
+func (c *TwoQueueCache) AddWithExpire(key string, value common.Value, expire time.Time) {
+	if ele, ok := c.items[key]; ok {
+		c.update(ele, value, expire)
+		if ele.Value.(*segEntry).seg == twoQMain {
+			c.lists[twoQMain].MoveToFront(ele)
+		}
+	} else if c.out.remove(key) {
+		c.push(twoQMain, key, value, expire)
+	} else {
+		c.push(twoQIn, key, value, expire)
+	}
+	for c.maxBytes != 0 && c.maxBytes < c.nbytes {
+		c.RemoveOldest()
+	}
+	c.out.trim(c.capacity() * twoQOutPercent / 100)
+}
+
+// RemoveOldest 淘汰一个条目：A1in 超出份额或者 Am 为空时淘汰 A1in 中最早进入的条目并记入 A1out，
+// 否则淘汰 Am 中最久未访问的条目
+func (c *TwoQueueCache) RemoveOldest() {
+	in, main := c.lists[twoQIn], c.lists[twoQMain]
+	switch {
+	case in.Len() > 0 && (c.bytes[twoQIn] > c.capacity()*twoQInPercent/100 || main.Len() == 0):
+		e := c.removeElement(in.Back())
+		c.out.push(e.key, e.size())
+		c.out.trim(c.capacity() * twoQOutPercent / 100)
+	case main.Len() > 0:
+		c.removeElement(main.Back())
+	}
+}
+
+// capacity 返回容量，不限制容量时使用 SetCapacity 设置的容量，见 segments.limit
+func (c *TwoQueueCache) capacity() int64 {
+	return c.limit(c.maxBytes)
+}
+
+// Bytes 返回当前占用的字节数，不包括 A1out
+func (c *TwoQueueCache) Bytes() int64 {
+	return c.nbytes
+}
+
+// Remove 删除指定 key 的条目，返回该 key 是否存在
+func (c *TwoQueueCache) Remove(key string) bool {
+	if ele, ok := c.items[key]; ok {
+		c.removeElement(ele)
+		return true
+	}
+	return false
+}
+
+// RemoveExpired 清理所有已过期的条目，返回清理的条目数
+func (c *TwoQueueCache) RemoveExpired() int {
+	eles := c.expired(time.Now())
+	for _, ele := range eles {
+		c.removeElement(ele)
+	}
+	return len(eles)
+}
+
+// removeElement 删除节点并触发 OnEvicted 回调，返回被删除的条目
+func (c *TwoQueueCache) removeElement(ele *list.Element) *segEntry {
+	e := c.remove(ele)
+	if c.OnEvicted != nil {
+		c.OnEvicted(e.key, e.value)
+	}
+	return e
+}
+
+// Len 返回缓存条目数
+func (c *TwoQueueCache) Len() int {
+	return len(c.items)
+}
@@ -0,0 +1,267 @@
+package cache
+
+import (
+	"GeeCache/geecache/common"
+	"GeeCache/geecache/data"
+	"container/list"
+	"hash/fnv"
+	"time"
+)
+
+// W-TinyLFU 各区域占容量的比例
+const (
+	tinyLFUWindowPercent    = 1  // 准入窗口占总容量的百分比
+	tinyLFUProtectedPercent = 80 // protected 段占主区域的百分比
+
+	tinyLFUMinWidth    = 256 // 频率估计器的最小宽度
+	tinyLFUSketchDepth = 4
+	tinyLFUSampleRatio = 10 // 每记录 宽度*10 次访问后衰减一次
+)
+
+// 条目所在的区域
+const (
+	segWindow = iota
+	segProbation
+	segProtected
+)
+
+// WTinyLFUCache 是一个 W-TinyLFU 缓存，非并发安全。
+//
+// 新条目先进入一个很小的 LRU 准入窗口；需要淘汰时，窗口中最久未访问的条目（候选者）
+// 与主区域中最久未访问的条目（牺牲者）比较访问频率，频率更高的留下，因此一次性扫描不会挤掉热点数据。
+// 主区域是分段 LRU：新进入的条目在 probation 段，再次命中后升入 protected 段。
+// 访问频率由 Count-Min Sketch 估计，前面有一个 doorkeeper 过滤只出现一次的 key，
+// 每记录一定次数的访问后所有计数减半，使过去的热点逐渐被遗忘。
+//
+// maxBytes 为 0 时不主动淘汰，由调用方（例如 Accountant）调用 RemoveOldest，
+// 此时各区域的大小按 SetCapacity 设置的容量计算，没有设置时按当前占用的字节数计算。
+type WTinyLFUCache struct {
+	maxBytes int64
+	segments // 三个段分别是准入窗口、probation 段和 protected 段
+
+	sketch    *data.CountMinSketch
+	door      *doorkeeper
+	width     int // 频率估计器的宽度，条目数超过宽度时扩大
+	additions int // 上次衰减之后记录的访问次数
+
+	// 某条记录被移除时的回调函数，可为 nil
+	OnEvicted func(key string, value common.Value)
+}
+
+// NewWTinyLFUCache 创建一个 W-TinyLFU 缓存实例，maxBytes 为 0 表示不限制
+func NewWTinyLFUCache(maxBytes int64, onEvicted func(string, common.Value)) *WTinyLFUCache {
+	c := &WTinyLFUCache{
+		maxBytes:  maxBytes,
+		segments:  newSegments(3),
+		OnEvicted: onEvicted,
+	}
+	c.resizeSketch(tinyLFUMinWidth)
+	return c
+}
+
+// Get 获取缓存条目，无论是否命中都记录一次访问
+func (c *WTinyLFUCache) Get(key string) (value common.Value, ok bool) {
+	c.record(key)
+	ele, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	e := ele.Value.(*segEntry)
+	// 已过期的条目视为未命中，并惰性删除
+	if expired(e.expire, time.Now()) {
+		c.removeElement(ele)
+		return nil, false
+	}
+	c.touch(ele)
+	return e.value, true
+}
+
+// Add 添加或更新缓存条目
+func (c *WTinyLFUCache) Add(key string, value common.Value) {
+	c.AddWithExpire(key, value, time.Time{})
+}
+
+// AddWithExpire 添加或更新一个在 expire 时刻过期的条目，expire 为零值表示永不过期。
+// 新条目进入准入窗口，是否进入主区域在淘汰时决定。
+func (c *WTinyLFUCache) AddWithExpire(key string, value common.Value, expire time.Time) {
+	if ele, ok := c.items[key]; ok {
+		c.update(ele, value, expire)
+		c.touch(ele)
+	} else {
+		c.push(segWindow, key, value, expire)
+		if len(c.items) > c.width {
+			c.resizeSketch(2 * len(c.items))
+		}
+	}
+	for c.maxBytes != 0 && c.maxBytes < c.nbytes {
+		c.RemoveOldest()
+	}
+}
+
+// RemoveOldest 淘汰一个条目。
+// 准入窗口超出份额时，窗口中的候选者先在主区域有空间时直接进入 probation 段；
+// 主区域已满时与主区域的牺牲者比较频率，淘汰频率较低的一方。
+// 窗口没有超出份额时淘汰主区域的牺牲者。
+func (c *WTinyLFUCache) RemoveOldest() {
+	if len(c.items) == 0 {
+		return
+	}
+	capacity := c.capacity()
+	windowMax := capacity * tinyLFUWindowPercent / 100
+	mainMax := capacity - windowMax
+
+	window := c.lists[segWindow]
+	for c.bytes[segWindow] > windowMax {
+		candidate := window.Back()
+		ce := candidate.Value.(*segEntry)
+		if c.bytes[segProbation]+c.bytes[segProtected]+ce.size() <= mainMax {
+			c.moveTo(candidate, segProbation)
+			continue
+		}
+		// 频率相同时留下主区域中的条目，避免新数据挤掉已有的数据
+		victim := c.victim()
+		if victim != nil && c.frequency(ce.key) > c.frequency(victim.Value.(*segEntry).key) {
+			c.moveTo(candidate, segProbation)
+			c.removeElement(victim)
+		} else {
+			c.removeElement(candidate)
+		}
+		return
+	}
+	if victim := c.victim(); victim != nil {
+		c.removeElement(victim)
+		return
+	}
+	c.removeElement(window.Back())
+}
+
+// capacity 返回计算各区域大小时使用的容量，不限制容量时使用 SetCapacity 设置的容量，见 segments.limit
+func (c *WTinyLFUCache) capacity() int64 {
+	return c.limit(c.maxBytes)
+}
+
+// victim 返回主区域中最先被淘汰的条目：probation 段最久未访问的条目，probation 为空时取 protected 段的
+func (c *WTinyLFUCache) victim() *list.Element {
+	if ele := c.lists[segProbation].Back(); ele != nil {
+		return ele
+	}
+	return c.lists[segProtected].Back()
+}
+
+// touch 处理一次命中：probation 段的条目升入 protected 段，其他条目移到所在链表的最前面
+func (c *WTinyLFUCache) touch(ele *list.Element) {
+	e := ele.Value.(*segEntry)
+	if e.seg != segProbation {
+		c.lists[e.seg].MoveToFront(ele)
+		return
+	}
+	c.moveTo(ele, segProtected)
+	// protected 段超出份额时，把最久未访问的条目降回 probation 段
+	capacity := c.capacity()
+	protectedMax := (capacity - capacity*tinyLFUWindowPercent/100) * tinyLFUProtectedPercent / 100
+	for c.bytes[segProtected] > protectedMax && c.lists[segProtected].Len() > 1 {
+		c.moveTo(c.lists[segProtected].Back(), segProbation)
+	}
+}
+
+// Bytes 返回当前占用的字节数
+func (c *WTinyLFUCache) Bytes() int64 {
+	return c.nbytes
+}
+
+// Remove 删除指定 key 的条目，返回该 key 是否存在
+func (c *WTinyLFUCache) Remove(key string) bool {
+	if ele, ok := c.items[key]; ok {
+		c.removeElement(ele)
+		return true
+	}
+	return false
+}
+
+// RemoveExpired 清理所有已过期的条目，返回清理的条目数
+func (c *WTinyLFUCache) RemoveExpired() int {
+	eles := c.expired(time.Now())
+	for _, ele := range eles {
+		c.removeElement(ele)
+	}
+	return len(eles)
+}
+
+// removeElement 删除节点，并触发 OnEvicted 回调
+func (c *WTinyLFUCache) removeElement(ele *list.Element) {
+	e := c.remove(ele)
+	if c.OnEvicted != nil {
+		c.OnEvicted(e.key, e.value)
+	}
+}
+
+// Len 返回缓存条目数
+func (c *WTinyLFUCache) Len() int {
+	return len(c.items)
+}
+
+// record 记录一次对 key 的访问。第一次出现的 key 只记入 doorkeeper，再次出现才计入 sketch。
+func (c *WTinyLFUCache) record(key string) {
+	if c.door.add(key) {
+		c.sketch.Increment(key)
+	}
+	c.additions++
+	if c.additions >= c.width*tinyLFUSampleRatio {
+		c.sketch.Halve()
+		c.door.reset()
+		c.additions /= 2
+	}
+}
+
+// frequency 返回 key 的估计访问次数
+func (c *WTinyLFUCache) frequency(key string) uint32 {
+	n := c.sketch.Estimate(key)
+	if c.door.contains(key) {
+		n++
+	}
+	return n
+}
+
+// resizeSketch 按 width 重新创建频率估计器，已有的计数会丢失
+func (c *WTinyLFUCache) resizeSketch(width int) {
+	c.width = max(width, tinyLFUMinWidth)
+	c.sketch = data.NewCountMinSketch(c.width, tinyLFUSketchDepth)
+	c.door = newDoorkeeper(c.width)
+	c.additions = 0
+}
+
+// doorkeeper 是一个布隆过滤器，记录在当前衰减周期内出现过的 key
+type doorkeeper struct {
+	bits []uint64
+}
+
+func newDoorkeeper(width int) *doorkeeper {
+	// 每个 key 约 8 位，使用 2 个哈希函数时误判率约 5%
+	return &doorkeeper{bits: make([]uint64, max(width/8, 1))}
+}
+
+func (d *doorkeeper) positions(key string) (uint64, uint64) {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	sum := h.Sum64()
+	n := uint64(len(d.bits)) * 64
+	return sum % n, (sum >> 32) % n
+}
+
+// add 把 key 加入过滤器，返回 key 之前是否已经存在
+func (d *doorkeeper) add(key string) bool {
+	i, j := d.positions(key)
+	seen := d.bits[i/64]&(1<<(i%64)) != 0 && d.bits[j/64]&(1<<(j%64)) != 0
+	d.bits[i/64] |= 1 << (i % 64)
+	d.bits[j/64] |= 1 << (j % 64)
+	return seen
+}
+
+func (d *doorkeeper) contains(key string) bool {
+	i, j := d.positions(key)
+	return d.bits[i/64]&(1<<(i%64)) != 0 && d.bits[j/64]&(1<<(j%64)) != 0
+}
+
+func (d *doorkeeper) reset() {
+	clear(d.bits)
+}
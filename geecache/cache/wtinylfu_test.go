@@ -0,0 +1,70 @@
+package cache_test
+
+import (
+	"GeeCache/geecache/cache"
+	"GeeCache/geecache/common"
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// 一次性扫描不会挤掉已经多次访问的热点数据
+func TestWTinyLFUScanResistance(t *testing.T) {
+	c := cache.NewWTinyLFUCache(100*8, nil)
+	hot := make([]string, 50)
+	for i := range hot {
+		hot[i] = fmt.Sprintf("hot%04d", i)
+	}
+	for round := 0; round < 5; round++ {
+		for _, key := range hot {
+			if _, ok := c.Get(key); !ok {
+				c.Add(key, MyValue{data: "v"})
+			}
+		}
+	}
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("scan%03d", i)
+		if _, ok := c.Get(key); !ok {
+			c.Add(key, MyValue{data: "v"})
+		}
+	}
+	kept := 0
+	for _, key := range hot {
+		if _, ok := c.Get(key); ok {
+			kept++
+		}
+	}
+	if kept < len(hot)*9/10 {
+		t.Fatalf("expected most hot keys to survive a scan, kept %d of %d", kept, len(hot))
+	}
+}
+
+// 随机操作过程中占用的字节数不超过容量，删除所有条目后回到 0
+func TestWTinyLFUByteAccounting(t *testing.T) {
+	evicted := 0
+	c := cache.NewWTinyLFUCache(1024, func(string, common.Value) { evicted++ })
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 10000; i++ {
+		key := fmt.Sprintf("key%d", r.Intn(500))
+		switch r.Intn(4) {
+		case 0:
+			c.Remove(key)
+		case 1:
+			c.Get(key)
+		default:
+			c.Add(key, MyValue{data: string(make([]byte, r.Intn(64)))})
+		}
+		if c.Bytes() > 1024 {
+			t.Fatalf("cache holds %d bytes, over its 1024 byte capacity", c.Bytes())
+		}
+	}
+	if c.Len() == 0 || evicted == 0 {
+		t.Fatalf("expected both cached and evicted entries, got %d cached, %d evicted", c.Len(), evicted)
+	}
+	for i := 0; i < 500; i++ {
+		c.Remove(fmt.Sprintf("key%d", i))
+	}
+	if c.Len() != 0 || c.Bytes() != 0 {
+		t.Fatalf("expected an empty cache, got %d entries in %d bytes", c.Len(), c.Bytes())
+	}
+}
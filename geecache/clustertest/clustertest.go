@@ -0,0 +1,279 @@
+// Package clustertest 在一个进程中通过 bufconn 启动多个 GeeCache 节点，用于测试节点间加载、
+// Raft 选举和故障切换。节点之间的每一次 RPC 都经过 Network，可以按链路注入分区、延迟和丢包。
+package clustertest
+
+import (
+	"GeeCache/geecache/distributed"
+	"GeeCache/geecache/interfaces"
+	"context"
+	"fmt"
+	"math/rand"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const bufSize = 1 << 20
+
+// Config 是集群的配置，零值字段使用默认值
+type Config struct {
+	Nodes             int           // 节点数，默认 3
+	ElectionTimeout   time.Duration // 默认 50ms
+	HeartbeatInterval time.Duration // 默认 10ms
+	Seed              int64         // 丢包使用的随机数种子，相同的种子得到相同的丢包序列
+}
+
+// Cluster 是一组通过 bufconn 互相连接的节点
+type Cluster struct {
+	t      testing.TB
+	Net    *Network
+	Nodes  []*Node
+	byName map[string]*Node
+}
+
+// Node 是集群中的一个节点，拥有自己的节点池、gRPC 服务和 Group
+type Node struct {
+	*distributed.Node
+	Addr string
+	Pool *distributed.GRPCPool
+	lis  *bufconn.Listener
+
+	mu      sync.Mutex
+	stopped bool
+}
+
+// New 启动一个集群，所有节点以全部节点为初始成员启动 Raft。测试结束时自动停止。
+func New(t testing.TB, cfg Config) *Cluster {
+	t.Helper()
+	if cfg.Nodes <= 0 {
+		cfg.Nodes = 3
+	}
+	if cfg.ElectionTimeout <= 0 {
+		cfg.ElectionTimeout = 50 * time.Millisecond
+	}
+	if cfg.HeartbeatInterval <= 0 {
+		cfg.HeartbeatInterval = 10 * time.Millisecond
+	}
+	c := &Cluster{t: t, Net: newNetwork(cfg.Seed), byName: make(map[string]*Node)}
+
+	var addrs []string
+	for i := 0; i < cfg.Nodes; i++ {
+		node := distributed.NewNode(fmt.Sprintf("node%d", i))
+		n := &Node{
+			Node: node,
+			Addr: node.Addr(),
+			Pool: node.Pool(),
+			lis:  bufconn.Listen(bufSize),
+		}
+		c.Nodes = append(c.Nodes, n)
+		c.byName[n.Addr] = n
+		addrs = append(addrs, n.Addr)
+	}
+	for _, n := range c.Nodes {
+		n.Pool.SetDialOptions(
+			grpc.WithContextDialer(c.dial),
+			grpc.WithUnaryInterceptor(c.Net.interceptor(n.Addr)),
+		)
+		n.Pool.SetRaftConfig(distributed.RaftConfig{
+			ElectionTimeout:   cfg.ElectionTimeout,
+			HeartbeatInterval: cfg.HeartbeatInterval,
+		})
+		n.Pool.Set(addrs...)
+		go n.Serve(n.lis)
+	}
+	t.Cleanup(func() {
+		for _, n := range c.Nodes {
+			n.Stop()
+		}
+	})
+	return c
+}
+
+// dial 把地址解析为对应节点的 bufconn 监听器
+func (c *Cluster) dial(ctx context.Context, addr string) (net.Conn, error) {
+	n, ok := c.byName[addr]
+	if !ok {
+		return nil, fmt.Errorf("clustertest: unknown node %q", addr)
+	}
+	return n.lis.DialContext(ctx)
+}
+
+// Node 按地址返回节点
+func (c *Cluster) Node(addr string) *Node {
+	return c.byName[addr]
+}
+
+// NewGroup 在每个节点上创建同名的 Group，getter 由 newGetter 为每个节点单独创建
+func (c *Cluster) NewGroup(name string, cacheBytes int64, algorithm string, newGetter func(n *Node) interfaces.Getter) {
+	for _, n := range c.Nodes {
+		n.NewGroup(name, cacheBytes, newGetter(n), algorithm)
+	}
+}
+
+// Leader 等待 except 之外的节点中恰好有一个领导者并返回它
+func (c *Cluster) Leader(except ...*Node) *Node {
+	c.t.Helper()
+	var leader *Node
+	c.WaitFor(2*time.Second, func() bool {
+		leader = nil
+	nodes:
+		for _, n := range c.Nodes {
+			for _, e := range except {
+				if n == e {
+					continue nodes
+				}
+			}
+			if _, isLeader := n.Pool.Raft().State(); isLeader && !n.isStopped() {
+				if leader != nil {
+					return false
+				}
+				leader = n
+			}
+		}
+		return leader != nil
+	})
+	return leader
+}
+
+// WaitFor 轮询 cond 直到返回 true，超时后测试失败
+func (c *Cluster) WaitFor(timeout time.Duration, cond func() bool) {
+	c.t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			c.t.Fatalf("clustertest: condition not met within %v", timeout)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// Partition 切断 a 与 b 两组节点之间双向的所有链路
+func (c *Cluster) Partition(a, b []*Node) {
+	for _, x := range a {
+		for _, y := range b {
+			c.Net.Block(x.Addr, y.Addr)
+			c.Net.Block(y.Addr, x.Addr)
+		}
+	}
+}
+
+// Isolate 切断 n 与其他所有节点之间的链路
+func (c *Cluster) Isolate(n *Node) {
+	var others []*Node
+	for _, o := range c.Nodes {
+		if o != n {
+			others = append(others, o)
+		}
+	}
+	c.Partition([]*Node{n}, others)
+}
+
+// Stop 停止节点的 gRPC 服务和 Raft，模拟节点崩溃
+func (n *Node) Stop() {
+	n.mu.Lock()
+	if n.stopped {
+		n.mu.Unlock()
+		return
+	}
+	n.stopped = true
+	n.mu.Unlock()
+	n.Node.Stop()
+	n.lis.Close()
+}
+
+func (n *Node) isStopped() bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.stopped
+}
+
+// link 是从 from 到 to 的单向链路
+type link struct {
+	from, to string
+}
+
+// Network 记录每条链路上的故障，在客户端拦截器中生效
+type Network struct {
+	mu      sync.Mutex
+	rng     *rand.Rand
+	blocked map[link]bool
+	delays  map[link]time.Duration
+	drops   map[link]float64
+}
+
+func newNetwork(seed int64) *Network {
+	return &Network{
+		rng:     rand.New(rand.NewSource(seed)),
+		blocked: make(map[link]bool),
+		delays:  make(map[link]time.Duration),
+		drops:   make(map[link]float64),
+	}
+}
+
+// Block 切断从 from 到 to 的链路
+func (n *Network) Block(from, to string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.blocked[link{from, to}] = true
+}
+
+// Delay 让从 from 到 to 的每个请求在发送前等待 d
+func (n *Network) Delay(from, to string, d time.Duration) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.delays[link{from, to}] = d
+}
+
+// Drop 让从 from 到 to 的请求以概率 p 丢失
+func (n *Network) Drop(from, to string, p float64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.drops[link{from, to}] = p
+}
+
+// Heal 清除所有故障
+func (n *Network) Heal() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	clear(n.blocked)
+	clear(n.delays)
+	clear(n.drops)
+}
+
+// fault 决定一次从 from 到 to 的请求是否失败以及需要延迟多久
+func (n *Network) fault(from, to string) (time.Duration, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	l := link{from, to}
+	if n.blocked[l] {
+		return 0, status.Errorf(codes.Unavailable, "clustertest: %s -> %s partitioned", from, to)
+	}
+	if p := n.drops[l]; p > 0 && n.rng.Float64() < p {
+		return 0, status.Errorf(codes.Unavailable, "clustertest: %s -> %s dropped", from, to)
+	}
+	return n.delays[l], nil
+}
+
+// interceptor 返回 from 节点的客户端拦截器，目标节点取自连接的地址
+func (n *Network) interceptor(from string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		delay, err := n.fault(from, cc.Target())
+		if err != nil {
+			return err
+		}
+		if delay > 0 {
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return status.FromContextError(ctx.Err()).Err()
+			}
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
@@ -2,12 +2,28 @@ package core
 
 import (
 	"GeeCache/geecache/cache"
+	"GeeCache/geecache/common"
 	"GeeCache/geecache/data"
 	pb "GeeCache/geecache/geecachepb"
 	"GeeCache/geecache/interfaces"
+	"GeeCache/geecache/metrics"
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"math/rand"
 	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	sweepInterval       = time.Minute // 后台清理过期数据的最大间隔
+	defaultReplicaCount = 3           // 热点同步、删除时涉及的副本节点数
+	hotCacheRatio       = 8           // hotCache 的默认容量为 mainCache 的 1/8
+	hotCacheShards      = 16          // hotCache 较小，使用较少的分片
+	hotCacheAdmitChance = 10          // 非热点的远程数据以 1/10 的概率进入 hotCache
+	defaultHotCacheTTL  = time.Minute // hotCache 中数据的默认过期时间
 )
 
 // A Group is a cache namespace and associated data loaded spread over
@@ -15,62 +31,155 @@ import (
 type Group struct {
 	name      string
 	getter    interfaces.Getter   // 缓存未命中时获取源数据的回调(callback)。
-	maincache *cache.ShardedCache // 一开始实现的并发缓存
-	peers     interfaces.PeerPicker
+	maincache *cache.ShardedCache // 一开始实现的并发缓存，保存本节点拥有的 key
+	// hotcache 保存从其他节点获取的热门数据，避免每次请求都走一次 gRPC。
+	// 容量比 mainCache 小，数据按概率准入，防止挤占本节点拥有的数据。
+	hotcache *cache.ShardedCache
+	peers    interfaces.PeerPicker
 	// 使用singleflight.Group确保每个键只被获取一次
 	loader      *RequestGroup
-	hotKeys     map[string]int // 热点 Key 的访问统计
-	hotKeyMutex sync.RWMutex   // 使用读写锁     // 防止并发访问冲突
-}
+	hotKeys     *hotKeyDetector // 热点 Key 的访问统计，带衰减
+	ttl         atomic.Int64    // 默认过期时间（纳秒），0 表示永不过期
+	hotTTL      time.Duration   // hotCache 中数据的最长存活时间
+	sweeperOnce sync.Once       // 保证后台清理协程只启动一次
+	loadTimeout time.Duration   // 每次加载的超时时间，0 表示不限制
+	hooks       GroupHooks
+	stats       groupCounters
+	log         *slog.Logger       // 带有 group 属性的 logger
+	policy      string             // 淘汰算法的名字，用于指标的标签
+	loadLatency *metrics.Histogram // 缓存未命中时每次加载的耗时
 
-var (
-	mu     sync.RWMutex
-	groups = make(map[string]*Group) // 将 group 存储在全局变量 groups 中
-)
+	// 其他节点转发来的请求使用单独的 singleflight。转发来的请求只在本地加载，不会等待其他节点；
+	// 与本节点发出的请求共用时，两个节点同时把同一个热点 key 转发给对方会互相等待直到超时。
+	forwardLoader *RequestGroup
+}
 
 // NewGroup create a new instance of Group
+// 新建的 Group 注册在 DefaultRegistry 中，需要多个互相独立的节点时使用各自的 Registry
 func NewGroup(name string, cacheBytes int64, getter interfaces.Getter, algorithm string) *Group {
+	return DefaultRegistry.NewGroup(name, cacheBytes, getter, algorithm)
+}
+
+// NewGroupWithOptions 与 NewGroup 相同，但通过 opts 配置 Group，配置不合法时返回错误而不是 panic
+func NewGroupWithOptions(name string, cacheBytes int64, getter interfaces.Getter, opts ...GroupOption) (*Group, error) {
+	return DefaultRegistry.NewGroupWithOptions(name, cacheBytes, getter, opts...)
+}
+
+// newGroup 创建 Group，但不注册到任何 Registry
+func newGroup(name string, cacheBytes int64, getter interfaces.Getter, opts []GroupOption) (*Group, error) {
+	if name == "" {
+		return nil, errors.New("group name is required")
+	}
 	if getter == nil {
-		panic("nil Getter")
+		return nil, errors.New("nil Getter")
+	}
+	if cacheBytes < 0 {
+		return nil, fmt.Errorf("cache size must not be negative, got %d", cacheBytes)
+	}
+	o, err := newGroupOptions(cacheBytes, opts)
+	if err != nil {
+		return nil, err
 	}
-	mu.Lock()
-	defer mu.Unlock()
 
 	// 创建一个带有分片的缓存，支持不同的缓存算法（LRU、LFU等）
-	mainCache := cache.NewShardedCache(256, cacheBytes, algorithm)
+	mainCache, err := cache.NewShardedCacheWithConfig(cache.ShardedCacheConfig{
+		Shards:     o.Shards,
+		CacheBytes: cacheBytes,
+		Algorithm:  o.Policy,
+		OnEvicted:  evictHook(o.Hooks.OnEvict),
+		Parent:     o.Accountant,
+	})
+	if err != nil {
+		return nil, err
+	}
+	hotCache, err := cache.NewShardedCacheWithConfig(cache.ShardedCacheConfig{
+		Shards:     hotCacheShards,
+		CacheBytes: o.HotCacheBytes,
+		Algorithm:  o.Policy,
+		Parent:     o.Accountant,
+	})
+	if err != nil {
+		return nil, err
+	}
 
 	// 创建新的 Group 对象
 	g := &Group{
-		name:      name,
-		getter:    getter,
-		maincache: mainCache, // 使用传入的分片缓存
-		loader:    &RequestGroup{},
-		hotKeys:   make(map[string]int), // 初始化热点统计
+		name:        name,
+		getter:      getter,
+		maincache:   mainCache, // 使用传入的分片缓存
+		hotcache:    hotCache,
+		loader:      &RequestGroup{},
+		hotKeys:     newHotKeyDetector(), // 初始化热点统计
+		hotTTL:      o.HotCacheTTL,
+		loadTimeout: o.LoadTimeout,
+		hooks:       o.Hooks,
+		log:         o.Logger.With("group", name),
+		policy:      o.Policy,
+		loadLatency: metrics.NewHistogram(),
+
+		forwardLoader: &RequestGroup{},
+	}
+	g.SetTTL(o.TTL)
+	return g, nil
+}
+
+// evictHook 把 OnEvict 转换为缓存的淘汰回调
+func evictHook(onEvict func(string, data.ByteView)) func(string, common.Value) {
+	if onEvict == nil {
+		return nil
+	}
+	return func(key string, value common.Value) {
+		onEvict(key, value.(data.ByteView))
 	}
-	// 将新创建的 Group 注册到 groups 中
-	groups[name] = g
-	return g
 }
 
 // GetGroup returns the named group previously created with NewGroup
 // or nil if there is no such group
 func GetGroup(name string) *Group {
-	// if groups[name] != nil {
-	// 	return groups[name]
-	// }else {return nil}
+	return DefaultRegistry.GetGroup(name)
+}
+
+// DeleteGroup 从 DefaultRegistry 中删除 Group，返回该 Group 是否存在
+func DeleteGroup(name string) bool {
+	return DefaultRegistry.DeleteGroup(name)
+}
 
-	// 使用了只读锁 RLock()，因为不涉及任何冲突变量的写操作。
-	mu.RLock()
-	g := groups[name]
-	mu.RUnlock()
-	return g
+// ListGroups 返回 DefaultRegistry 中所有 Group 的名字，按字典序排列
+func ListGroups() []string {
+	return DefaultRegistry.ListGroups()
+}
+
+// Name 返回 Group 的名字
+func (g *Group) Name() string {
+	return g.name
 }
 
 // value for key from cache
 func (g *Group) Get(key string) (data.ByteView, error) {
+	return g.GetContext(context.Background(), key)
+}
+
+// GetContext 与 Get 相同，但 ctx 的截止时间和取消信号会传递给 singleflight、
+// 远程节点的 gRPC 调用以及实现了 GetterWithContext 的回调函数。
+func (g *Group) GetContext(ctx context.Context, key string) (data.ByteView, error) {
+	return g.get(ctx, key, false)
+}
+
+// GetForwarded 处理其他节点的 Group 转发来的请求：只读本地缓存，未命中时在本地加载，不再选择远程节点。
+// 发送方已经选好了节点（拥有者、有界负载下的其他节点或热点 key 的副本），
+// 接收方再次选择节点会把请求送回拥有者甚至形成环路。
+func (g *Group) GetForwarded(ctx context.Context, key string) (data.ByteView, error) {
+	return g.get(ctx, key, true)
+}
+
+func (g *Group) get(ctx context.Context, key string, forwarded bool) (data.ByteView, error) {
 	if key == "" {
 		return data.ByteView{}, fmt.Errorf("key is required")
 	}
+	if err := ctx.Err(); err != nil {
+		return data.ByteView{}, err
+	}
+	g.stats.gets.Add(1)
 	g.IncrementKeyUsage(key) // 增加访问计数
 
 	//流程 ⑴ ：从 mainCache 中查找缓存，如果存在则返回缓存值。
@@ -78,62 +187,306 @@ func (g *Group) Get(key string) (data.ByteView, error) {
 	通过 ByteSlice() 或 String() 方法取到缓存值的副本。
 	只读属性，是设计 core.ByteView 的主要目的之一。*/
 	if v, ok := g.maincache.Get(key); ok {
-		log.Printf("[GeeCache] Cache hit for key: %s", key)
+		g.debug(ctx, "cache hit", key)
+		g.onHit(key)
+		g.maybeReplicateHotKey(key, v.(data.ByteView))
+		return v.(data.ByteView), nil
+	}
+	// 流程 ⑵ ：从 hotCache 中查找其他节点拥有的热门数据
+	if v, ok := g.hotcache.Get(key); ok {
+		g.debug(ctx, "hot cache hit", key)
+		g.onHit(key)
 		return v.(data.ByteView), nil
 	}
 
-	log.Printf("[GeeCache] Cache miss for key: %s, loading...", key)
-	// 流程 ⑶ ：缓存不存在，则调用 load 方法，
-	return g.load(key)
+	g.debug(ctx, "cache miss", key)
+	g.stats.misses.Add(1)
+	if g.hooks.OnMiss != nil {
+		g.hooks.OnMiss(key)
+	}
+	// 流程 ⑶ ：缓存不存在，则调用 load 方法，等待时间受 loadTimeout 限制
+	if g.loadTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.loadTimeout)
+		defer cancel()
+	}
+	start := time.Now()
+	value, err := g.load(ctx, key, forwarded)
+	elapsed := time.Since(start)
+	g.loadLatency.ObserveDuration(elapsed)
+	if g.hooks.OnLoad != nil {
+		g.hooks.OnLoad(key, elapsed, err)
+	}
+	return value, err
+}
+
+// debug 记录读写路径上的事件。先检查级别，未开启 Debug 时不构造日志记录。
+func (g *Group) debug(ctx context.Context, msg, key string) {
+	if g.log.Enabled(ctx, slog.LevelDebug) {
+		g.log.LogAttrs(ctx, slog.LevelDebug, msg, slog.String("key", key))
+	}
+}
+
+func (g *Group) onHit(key string) {
+	g.stats.hits.Add(1)
+	if g.hooks.OnHit != nil {
+		g.hooks.OnHit(key)
+	}
 }
 
 // load 调用 getLocally（分布式场景下会调用 getFromPeer 从其他节点获取），
 // 使用 PickPeer() 方法选择节点，若非本机节点，则调用 getFromPeer() 从远程获取。
-// 若是本机节点或失败，则回退到 getLocally()。
+// 若是本机节点或失败，则回退到 getLocally()。forwarded 为 true 时直接调用 getLocally()。
 // 能有效分散请求压力，同时保证即便远程节点出问题，系统仍然能正常工作。
-func (g *Group) load(key string) (value data.ByteView, err error) {
-	// 使用 g.loader.Do 包裹起来,确保并发场景下针对相同的 key，load 过程只会调用一次。
-	viewi, err := g.loader.Do(key, func() (interface{}, error) {
-		// 判断缓存系统是否有配置其他可用节点。如果没有其他节点，直接走本地获取的流程。
-		if g.peers != nil {
-			// 有可用的节点，则通过调用 PickPeer(key) 选择一个节点
-			if peer, ok := g.peers.PickPeer(key); ok {
-				log.Printf("[GeeCache] Trying to load key: %s from peer", key)
+func (g *Group) load(ctx context.Context, key string, forwarded bool) (value data.ByteView, err error) {
+	// 使用 g.loader.DoContext 包裹起来,确保并发场景下针对相同的 key，load 过程只会调用一次。
+	// 加载不随某一个调用方取消，只受 loadTimeout 限制，见 RequestGroup.DoContext。
+	loader := g.loader
+	if forwarded {
+		loader = g.forwardLoader
+	}
+	viewi, err, shared := loader.DoContext(ctx, key, func(ctx context.Context) (interface{}, error) {
+		if g.loadTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, g.loadTimeout)
+			defer cancel()
+		}
+		// 判断缓存系统是否有配置其他可用节点。如果没有其他节点，或者请求是其他节点转发来的，直接走本地获取的流程。
+		if g.peers != nil && !forwarded {
+			// 有可用的节点，则通过调用 pickPeer(key) 选择一个节点
+			if peer, ok := g.pickPeer(key); ok {
+				g.debug(ctx, "loading from peer", key)
 				// 调用 getFromPeer(peer, key) 从远程节点获取数据
-				if value, err = g.getFromPeer(peer, key); err == nil {
-					// 成功，则返回远程获取到的数据
+				if value, err = g.getFromPeer(ctx, peer, key); err == nil {
+					g.stats.peerLoads.Add(1)
+					// 成功，则按概率放入 hotCache 后返回远程获取到的数据
+					if g.shouldAdmitHot(key) {
+						g.populateHotCache(key, value, 0)
+					}
 					return value, nil
 				}
+				g.stats.peerErrors.Add(1)
+				// 加载已经超时，或者所有调用方都已放弃等待，不再回退到本地获取
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
 				// 失败，则记录日志并回退到本地获取流程。
-				log.Printf("[GeeCache] Failed to load key: %s from peer, error: %v", key, err)
+				g.log.LogAttrs(ctx, slog.LevelWarn, "failed to load from peer, loading locally",
+					slog.String("key", key), slog.Any("error", err))
 			}
 		}
-		log.Printf("[GeeCache] Loading key: %s locally", key)
+		g.debug(ctx, "loading locally", key)
 		// 没有找到合适的远程节点，或者从远程节点获取数据失败，则调用 g.getLocally(key) 进行本地获取。
-		return g.getLocally(key)
+		return g.getLocally(ctx, key, forwarded)
 	})
+	if shared {
+		g.stats.dedups.Add(1)
+	}
 	if err == nil {
 		return viewi.(data.ByteView), nil
 	}
-	return data.ByteView{}, fmt.Errorf("failed to load key: %s, error: %v", key, err)
+	return data.ByteView{}, fmt.Errorf("failed to load key: %s, error: %w", key, err)
 
 }
 
 // getLocally 调用用户回调函数 g.getter.Get() 获取源数据，
-func (g *Group) getLocally(key string) (data.ByteView, error) {
-	bytes, err := g.getter.Get(key)
+// 若 getter 实现了 GetterWithContext，则把 ctx 传给回调函数；
+// 若 getter 实现了 TTLGetter，则使用其返回的 ttl 作为该 key 的过期时间。
+// 其他节点转发来的不归本节点所有的 key 写入 hotCache，见 populateHotCache。
+func (g *Group) getLocally(ctx context.Context, key string, forwarded bool) (data.ByteView, error) {
+	var (
+		bytes []byte
+		ttl   time.Duration
+		err   error
+	)
+	// 同时支持 ctx 和 TTL 的回调优先，这样两者都不会丢失
+	if ctg, ok := g.getter.(interfaces.TTLGetterWithContext); ok {
+		bytes, ttl, err = ctg.GetWithTTLContext(ctx, key)
+	} else if cg, ok := g.getter.(interfaces.GetterWithContext); ok {
+		bytes, err = cg.GetContext(ctx, key)
+	} else if tg, ok := g.getter.(interfaces.TTLGetter); ok {
+		bytes, ttl, err = tg.GetWithTTL(key)
+	} else {
+		bytes, err = g.getter.Get(key)
+	}
 	if err != nil {
+		g.stats.localLoadErrors.Add(1)
 		return data.ByteView{}, err
 	}
+	g.stats.localLoads.Add(1)
 
 	// 将源数据添加到缓存 mainCache 中（通过 populateCache 方法）
 	value := data.ByteView{B: data.CloneBytes(bytes)}
-	g.populateCache(key, value)
+	// 有界负载模式下拥有者超载时，请求会被转给环上的其他节点。这些节点通常不在 Remove 通知的范围内，
+	// 写入 mainCache 会一直返回旧值，还会占用本节点拥有的 key 的预算
+	if forwarded && !g.ownsKey(key) {
+		g.populateHotCache(key, value, ttl)
+		return value, nil
+	}
+	g.populateCache(key, value, ttl)
+	g.maybeReplicateHotKey(key, value)
 	return value, nil
 }
 
-func (g *Group) populateCache(key string, value data.ByteView) {
-	g.maincache.Add(key, value)
+// ownsKey 返回 key 是否归本节点所有，没有注册其他节点时所有 key 都归本节点所有
+func (g *Group) ownsKey(key string) bool {
+	if g.peers == nil {
+		return true
+	}
+	_, remote := g.peers.PickPeer(key)
+	return !remote
+}
+
+// pickPeer 选择加载 key 的远程节点。热点 key 的读请求会分散到拥有者和副本节点上，
+// 本节点是拥有者时返回 false。
+func (g *Group) pickPeer(key string) (interfaces.PeerGetter, bool) {
+	peer, ok := g.peers.PickPeer(key)
+	if !ok || !g.IsHotKey(key) {
+		return peer, ok
+	}
+	if replicatedPicker, isReplicated := g.peers.(interfaces.ReplicatedPeerPicker); isReplicated {
+		if replica := replicatedPicker.SelectPeer(replicatedPicker.GetReplicatedPeers(key, defaultReplicaCount)); replica != nil {
+			return replica, true
+		}
+	}
+	return peer, ok
+}
+
+// populateCache 将数据写入 mainCache，ttl <= 0 时使用 Group 的默认 TTL
+func (g *Group) populateCache(key string, value data.ByteView, ttl time.Duration) {
+	g.populate(g.maincache, key, value, ttl)
+}
+
+// populateHotCache 将其他节点拥有的数据写入 hotCache，ttl <= 0 时使用 Group 的默认 TTL，但不超过 hotTTL。
+// Remove 只通知拥有者和副本节点，其他节点 hotCache 中的旧值只能依靠过期失效。
+func (g *Group) populateHotCache(key string, value data.ByteView, ttl time.Duration) {
+	if ttl <= 0 {
+		ttl = g.TTL()
+	}
+	if ttl <= 0 || ttl > g.hotTTL {
+		ttl = g.hotTTL
+	}
+	g.populate(g.hotcache, key, value, ttl)
+}
+
+func (g *Group) populate(c *cache.ShardedCache, key string, value data.ByteView, ttl time.Duration) {
+	if ttl <= 0 {
+		ttl = g.TTL()
+	}
+	if ttl <= 0 {
+		c.Add(key, value)
+		return
+	}
+	g.startSweeper(ttl)
+	c.AddWithExpire(key, value, time.Now().Add(ttl))
+}
+
+// shouldAdmitHot 决定远程数据是否进入 hotCache：热点 key 总是准入，其余按概率准入
+func (g *Group) shouldAdmitHot(key string) bool {
+	return g.IsHotKey(key) || rand.Intn(hotCacheAdmitChance) == 0
+}
+
+// SetTTL 设置 Group 的默认过期时间，ttl <= 0 表示永不过期。只影响之后写入的数据。
+func (g *Group) SetTTL(ttl time.Duration) {
+	if ttl < 0 {
+		ttl = 0
+	}
+	g.ttl.Store(int64(ttl))
+}
+
+// TTL 返回 Group 的默认过期时间
+func (g *Group) TTL() time.Duration {
+	return time.Duration(g.ttl.Load())
+}
+
+// startSweeper 在第一次写入带过期时间的数据时启动后台清理协程
+func (g *Group) startSweeper(ttl time.Duration) {
+	g.sweeperOnce.Do(func() {
+		interval := sweepInterval
+		if ttl < interval {
+			interval = ttl
+		}
+		g.maincache.StartJanitor(interval)
+		g.hotcache.StartJanitor(interval)
+	})
+}
+
+// Bytes 返回 mainCache 和 hotCache 当前占用的字节数
+func (g *Group) Bytes() int64 {
+	return g.maincache.Bytes() + g.hotcache.Bytes()
+}
+
+// stop 停止后台清理协程并释放共享的内存预算，Group 从 Registry 中删除时调用。
+// 占用 sweeperOnce，删除后继续使用 Group 也不会再启动清理协程。
+func (g *Group) stop() {
+	g.sweeperOnce.Do(func() {})
+	g.maincache.Close()
+	g.hotcache.Close()
+}
+
+// Remove 删除 key：先删除本地缓存，再通知拥有该 key 的节点以及副本节点删除，
+// 避免其他节点继续返回旧值。其余节点 hotCache 中的副本在 HotCacheTTL 之内过期。
+func (g *Group) Remove(key string) error {
+	return g.RemoveContext(context.Background(), key)
+}
+
+// RemoveContext 与 Remove 相同，ctx 会传递给远程节点的 Delete 调用
+func (g *Group) RemoveContext(ctx context.Context, key string) error {
+	if key == "" {
+		return fmt.Errorf("key is required")
+	}
+	g.RemoveLocal(key)
+	if g.peers == nil {
+		return nil
+	}
+
+	// 收集拥有者节点和副本节点，同一个节点只通知一次
+	var targets []interfaces.PeerGetter
+	seen := make(map[interfaces.PeerGetter]bool)
+	add := func(peer interfaces.PeerGetter) {
+		if peer != nil && !seen[peer] {
+			seen[peer] = true
+			targets = append(targets, peer)
+		}
+	}
+	if peer, ok := g.peers.PickPeer(key); ok {
+		add(peer)
+	}
+	if replicatedPicker, ok := g.peers.(interfaces.ReplicatedPeerPicker); ok {
+		for _, peer := range replicatedPicker.GetReplicatedPeers(key, defaultReplicaCount) {
+			add(peer)
+		}
+	}
+
+	var (
+		wg   sync.WaitGroup
+		emu  sync.Mutex
+		errs []error
+	)
+	for _, peer := range targets {
+		wg.Add(1)
+		go func(p interfaces.PeerGetter) {
+			defer wg.Done()
+			req := &pb.Request{Group: g.name, Key: key}
+			if err := p.Delete(ctx, req, &pb.DeleteResponse{}); err != nil {
+				g.log.LogAttrs(ctx, slog.LevelWarn, "failed to remove key from peer",
+					slog.String("key", key), slog.Any("peer", p), slog.Any("error", err))
+				emu.Lock()
+				errs = append(errs, err)
+				emu.Unlock()
+			}
+		}(peer)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// RemoveLocal 只删除本节点缓存（mainCache 和 hotCache）中的 key，返回该 key 是否存在
+func (g *Group) RemoveLocal(key string) bool {
+	g.hotKeys.forget(key)
+	inMain := g.maincache.Remove(key)
+	inHot := g.hotcache.Remove(key)
+	return inMain || inHot
 }
 
 // RegisterPeers registers a PeerPicker for choosing remote peer
@@ -147,13 +500,14 @@ func (g *Group) RegisterPeers(peers interfaces.PeerPicker) {
 	g.peers = peers
 }
 
-func (g *Group) getFromPeer(peer interfaces.PeerGetter, key string) (data.ByteView, error) {
+func (g *Group) getFromPeer(ctx context.Context, peer interfaces.PeerGetter, key string) (data.ByteView, error) {
 	req := &pb.Request{
-		Group: g.name,
-		Key:   key,
+		Group:     g.name,
+		Key:       key,
+		Forwarded: true, // 接收方在本地处理，不再转发
 	}
 	res := &pb.Response{}
-	err := peer.Get(req, res)
+	err := peer.Get(ctx, req, res)
 	if err != nil {
 		return data.ByteView{}, err
 	}
@@ -162,29 +516,38 @@ func (g *Group) getFromPeer(peer interfaces.PeerGetter, key string) (data.ByteVi
 
 // IncrementKeyUsage 统计 Key 的访问次数
 func (g *Group) IncrementKeyUsage(key string) {
-	g.hotKeyMutex.Lock()
-	defer g.hotKeyMutex.Unlock()
-	// 将 hotKeys 的初始化提前到 Group 的构造函数中。
-	// if g.hotKeys == nil {
-	// 	g.hotKeys = make(map[string]int)
-	// }
-	g.hotKeys[key]++
+	g.hotKeys.record(key)
 }
 
-// IsHotKey 判断 Key 是否为热点
+// IsHotKey 判断 Key 是否为热点：衰减后的估计访问次数超过 hotKeyThreshold
 func (g *Group) IsHotKey(key string) bool {
-	g.hotKeyMutex.RLock() // 使用读锁
-	defer g.hotKeyMutex.RUnlock()
-	count, exists := g.hotKeys[key]
-	return exists && count > 100 // 假设访问次数超过 100 为热点
+	return g.hotKeys.isHot(key)
 }
 
+// SyncHotKeyToPeers 把热点 key 的值推送到副本节点的 hotCache 中，非热点 key 直接返回
 func (g *Group) SyncHotKeyToPeers(key string, value data.ByteView) error {
 	// 如果当前 key 不是热点，直接返回
 	if !g.IsHotKey(key) {
 		return nil
 	}
+	return g.pushToReplicas(context.Background(), key, value)
+}
 
+// maybeReplicateHotKey 在本节点提供热点 key 时异步推送到副本节点，
+// 同一个 key 在 hotKeyRepushInterval 内只推送一次。
+func (g *Group) maybeReplicateHotKey(key string, value data.ByteView) {
+	if g.peers == nil || !g.hotKeys.shouldPush(key) {
+		return
+	}
+	go func() {
+		if err := g.pushToReplicas(context.Background(), key, value); err != nil {
+			g.log.LogAttrs(context.Background(), slog.LevelWarn, "failed to replicate hot key",
+				slog.String("key", key), slog.Any("error", err))
+		}
+	}()
+}
+
+func (g *Group) pushToReplicas(ctx context.Context, key string, value data.ByteView) error {
 	/// 使用 ReplicatedPeerPicker 接口获取多个副本节点
 	replicatedPicker, ok := g.peers.(interfaces.ReplicatedPeerPicker)
 	if !ok {
@@ -192,37 +555,44 @@ func (g *Group) SyncHotKeyToPeers(key string, value data.ByteView) error {
 	}
 
 	// 获取多个副本节点
-	peers := replicatedPicker.GetReplicatedPeers(key, 3)
+	peers := replicatedPicker.GetReplicatedPeers(key, defaultReplicaCount)
 	if len(peers) == 0 {
 		return fmt.Errorf("no peers available for key: %s", key)
 	}
-	var wg sync.WaitGroup
-	var syncError error
-
+	var (
+		wg   sync.WaitGroup
+		emu  sync.Mutex
+		errs []error
+	)
 	for _, peer := range peers {
 		wg.Add(1)
 		go func(p interfaces.PeerGetter) {
 			defer wg.Done()
-			req := &pb.Request{Group: g.name, Key: key}
-			if err := p.Get(req, &pb.Response{}); err != nil {
-				log.Printf("[GeeCache] Failed to sync key: %s to peer: %v, error: %v", key, p, err)
-				syncError = err
+			if err := g.populateCacheOnPeer(ctx, p, key, value); err != nil {
+				g.log.LogAttrs(ctx, slog.LevelWarn, "failed to sync hot key to peer",
+					slog.String("key", key), slog.Any("peer", p), slog.Any("error", err))
+				emu.Lock()
+				errs = append(errs, err)
+				emu.Unlock()
 			}
 		}(peer)
 	}
 	wg.Wait()
-	return syncError
+	return errors.Join(errs...)
 }
 
-func (g *Group) populateCacheOnPeer(peer interfaces.PeerGetter, key string, value data.ByteView) error {
-	req := &pb.Request{Group: g.name, Key: key} // 修正没有 Value 字段的问题
-	res := &pb.Response{}
-	if err := peer.Get(req, res); err != nil {
-		return err
+// populateCacheOnPeer 通过 Push 调用把值写入远程节点的 hotCache
+func (g *Group) populateCacheOnPeer(ctx context.Context, peer interfaces.PeerGetter, key string, value data.ByteView) error {
+	req := &pb.PushRequest{
+		Group: g.name,
+		Key:   key,
+		Value: value.ByteSlice(),
+		TtlMs: g.TTL().Milliseconds(),
 	}
+	return peer.Push(ctx, req, &pb.PushResponse{})
+}
 
-	// 将同步数据添加到远程节点的缓存
-	remoteValue := data.ByteView{B: res.Value}
-	g.maincache.Add(key, remoteValue)
-	return nil
+// PopulateHotCache 把其他节点推送来的热点数据写入 hotCache，ttl <= 0 时使用 Group 的默认 TTL
+func (g *Group) PopulateHotCache(key string, value []byte, ttl time.Duration) {
+	g.populate(g.hotcache, key, data.ByteView{B: data.CloneBytes(value)}, ttl)
 }
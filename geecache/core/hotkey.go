@@ -0,0 +1,82 @@
+package core
+
+import (
+	"GeeCache/geecache/data"
+	"sync"
+	"time"
+)
+
+const (
+	hotKeyThreshold      = 100              // 估计访问次数超过该值的 key 视为热点
+	hotKeySketchWidth    = 4096             // Count-Min Sketch 每行的计数器个数
+	hotKeySketchDepth    = 4                // Count-Min Sketch 的行数
+	hotKeyDecayPeriod    = 10 * 1024        // 每记录这么多次访问，所有计数减半一次
+	hotKeyRepushInterval = 10 * time.Second // 同一个热点 key 两次推送到副本节点的最小间隔
+)
+
+// hotKeyDetector 使用带衰减的 Count-Min Sketch 统计 key 的访问频率，
+// 内存占用固定，旧的热度会随着时间衰减。
+type hotKeyDetector struct {
+	mu        sync.Mutex
+	sketch    *data.CountMinSketch
+	additions int
+	pushed    map[string]time.Time // 最近推送到副本节点的热点 key 及推送时间
+}
+
+func newHotKeyDetector() *hotKeyDetector {
+	return &hotKeyDetector{
+		sketch: data.NewCountMinSketch(hotKeySketchWidth, hotKeySketchDepth),
+		pushed: make(map[string]time.Time),
+	}
+}
+
+// record 记录一次访问，返回记录后的估计次数
+func (d *hotKeyDetector) record(key string) uint32 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	n := d.sketch.Increment(key)
+	d.additions++
+	if d.additions >= hotKeyDecayPeriod {
+		d.decayLocked()
+	}
+	return n
+}
+
+// decayLocked 计数减半，并清理早已过了推送间隔的记录，保证 pushed 不会无限增长
+func (d *hotKeyDetector) decayLocked() {
+	d.sketch.Halve()
+	d.additions = 0
+	now := time.Now()
+	for key, at := range d.pushed {
+		if now.Sub(at) >= hotKeyRepushInterval {
+			delete(d.pushed, key)
+		}
+	}
+}
+
+func (d *hotKeyDetector) isHot(key string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.sketch.Estimate(key) > hotKeyThreshold
+}
+
+// shouldPush 判断热点 key 是否需要（重新）推送到副本节点，需要时记录本次推送时间
+func (d *hotKeyDetector) shouldPush(key string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.sketch.Estimate(key) <= hotKeyThreshold {
+		return false
+	}
+	if at, ok := d.pushed[key]; ok && time.Since(at) < hotKeyRepushInterval {
+		return false
+	}
+	d.pushed[key] = time.Now()
+	return true
+}
+
+// forget 删除 key 的推送记录，key 被删除后需要重新推送
+func (d *hotKeyDetector) forget(key string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.pushed, key)
+}
@@ -0,0 +1,54 @@
+package core
+
+import (
+	"GeeCache/geecache/cache"
+	"GeeCache/geecache/metrics"
+)
+
+// Collect 实现 metrics.Collector，导出 r 中所有 Group 的指标
+func (r *Registry) Collect(w *metrics.Writer) {
+	for _, name := range r.ListGroups() {
+		if g := r.GetGroup(name); g != nil {
+			g.Collect(w)
+		}
+	}
+}
+
+// Collect 实现 metrics.Collector，导出 Group 的命中率、加载耗时以及每级缓存的容量和淘汰次数
+func (g *Group) Collect(w *metrics.Writer) {
+	s := g.Stats()
+	group := []string{"group", g.name}
+	counters := []struct {
+		name, help string
+		value      int64
+	}{
+		{"geecache_group_gets_total", "Number of Get calls.", s.Gets},
+		{"geecache_group_hits_total", "Number of Get calls served from the main or hot cache.", s.Hits},
+		{"geecache_group_misses_total", "Number of Get calls that had to load the value.", s.Misses},
+		{"geecache_group_peer_loads_total", "Number of values loaded from peers.", s.PeerLoads},
+		{"geecache_group_peer_errors_total", "Number of failed loads from peers.", s.PeerErrors},
+		{"geecache_group_local_loads_total", "Number of values loaded by the getter.", s.LocalLoads},
+		{"geecache_group_local_load_errors_total", "Number of failed getter calls.", s.LocalLoadErrors},
+		{"geecache_group_dedups_total", "Number of loads shared with a concurrent request.", s.Dedups},
+	}
+	for _, c := range counters {
+		w.Counter(c.name, c.help, float64(c.value), group...)
+	}
+	ratio := 0.0
+	if s.Gets > 0 {
+		ratio = float64(s.Hits) / float64(s.Gets)
+	}
+	w.Gauge("geecache_group_hit_ratio", "Fraction of Get calls served from the cache.", ratio, group...)
+	w.Histogram("geecache_group_load_duration_seconds", "Time spent loading values on a cache miss.", g.loadLatency, group...)
+
+	for _, tier := range []struct {
+		name  string
+		stats cache.Stats
+	}{{"main", s.MainCache}, {"hot", s.HotCache}} {
+		labels := []string{"group", g.name, "tier", tier.name}
+		w.Gauge("geecache_cache_bytes", "Bytes held by the cache tier.", float64(tier.stats.Bytes), labels...)
+		w.Gauge("geecache_cache_items", "Entries held by the cache tier.", float64(tier.stats.Items), labels...)
+		w.Counter("geecache_cache_evictions_total", "Entries evicted to stay within the memory budget.",
+			float64(tier.stats.Evictions), append(labels, "policy", g.policy)...)
+	}
+}
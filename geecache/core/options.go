@@ -0,0 +1,124 @@
+package core
+
+import (
+	"GeeCache/geecache/cache"
+	"GeeCache/geecache/data"
+	"GeeCache/geecache/logging"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+const defaultShards = 256 // mainCache 的默认分片数
+
+// GroupHooks 是 Group 在读取路径上调用的回调，字段为 nil 时不调用。
+// 回调在请求所在的协程中同步执行，不应阻塞。
+type GroupHooks struct {
+	OnHit  func(key string)                             // mainCache 或 hotCache 命中
+	OnMiss func(key string)                             // 缓存未命中，即将从其他节点或本地加载
+	OnLoad func(key string, d time.Duration, err error) // 一次加载结束，d 为加载耗时
+	// OnEvict 在数据从 mainCache 中删除（淘汰、过期或 Remove）时调用。
+	// 调用时持有分片的锁，不能在回调中访问同一个 Group。
+	OnEvict func(key string, value data.ByteView)
+}
+
+// GroupOptions 是创建 Group 的配置，零值字段使用默认值
+type GroupOptions struct {
+	Shards        int           // mainCache 的分片数，默认 256
+	Policy        string        // 淘汰算法，默认 "lru"
+	TTL           time.Duration // 默认过期时间，0 表示永不过期
+	HotCacheBytes int64         // hotCache 的容量，默认为 cacheBytes 的 1/8
+	HotCacheTTL   time.Duration // hotCache 中数据的最长存活时间，默认 1 分钟；Remove 后其他节点最多在这段时间内返回旧值
+	LoadTimeout   time.Duration // 每次加载（远程或本地）的超时时间，0 表示只受调用方 ctx 限制
+	Hooks         GroupHooks
+	// Accountant 是多个 Group 共享的内存预算，例如进程级的预算。
+	// 不为 nil 时 mainCache 和 hotCache 的内存占用同时计入其中，超出时从超出份额最多的 Group 中淘汰。
+	Accountant *cache.Accountant
+	// Logger 是 Group 使用的 logger，为 nil 时使用 logging.Default()。
+	// 每条日志都带有 group 属性，读写路径上的日志为 Debug 级别。
+	Logger *slog.Logger
+}
+
+// GroupOption 修改 GroupOptions
+type GroupOption func(*GroupOptions)
+
+// WithShards 设置 mainCache 的分片数
+func WithShards(n int) GroupOption {
+	return func(o *GroupOptions) { o.Shards = n }
+}
+
+// WithPolicy 设置淘汰算法，例如 "lru"、"lfu"、"wtinylfu"、"arc"、"2q"，或者通过 cache.RegisterPolicy 注册的算法
+func WithPolicy(name string) GroupOption {
+	return func(o *GroupOptions) { o.Policy = name }
+}
+
+// WithTTL 设置默认过期时间
+func WithTTL(ttl time.Duration) GroupOption {
+	return func(o *GroupOptions) { o.TTL = ttl }
+}
+
+// WithHotCacheBytes 设置 hotCache 的容量
+func WithHotCacheBytes(n int64) GroupOption {
+	return func(o *GroupOptions) { o.HotCacheBytes = n }
+}
+
+// WithHotCacheTTL 设置 hotCache 中数据的最长存活时间
+func WithHotCacheTTL(ttl time.Duration) GroupOption {
+	return func(o *GroupOptions) { o.HotCacheTTL = ttl }
+}
+
+// WithLoadTimeout 设置每次加载的超时时间
+func WithLoadTimeout(d time.Duration) GroupOption {
+	return func(o *GroupOptions) { o.LoadTimeout = d }
+}
+
+// WithHooks 设置 Group 的回调
+func WithHooks(hooks GroupHooks) GroupOption {
+	return func(o *GroupOptions) { o.Hooks = hooks }
+}
+
+// WithAccountant 让 Group 的内存占用计入多个 Group 共享的 Accountant
+func WithAccountant(a *cache.Accountant) GroupOption {
+	return func(o *GroupOptions) { o.Accountant = a }
+}
+
+// WithLogger 设置 Group 使用的 logger
+func WithLogger(l *slog.Logger) GroupOption {
+	return func(o *GroupOptions) { o.Logger = l }
+}
+
+// newGroupOptions 应用 opts 并填充默认值，配置不合法时返回错误
+func newGroupOptions(cacheBytes int64, opts []GroupOption) (GroupOptions, error) {
+	var o GroupOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.Shards == 0 {
+		o.Shards = defaultShards
+	}
+	if o.Logger == nil {
+		o.Logger = logging.Default()
+	}
+	if o.Policy == "" {
+		o.Policy = "lru"
+	}
+	if o.HotCacheBytes == 0 {
+		o.HotCacheBytes = cacheBytes / hotCacheRatio
+	}
+	if o.HotCacheTTL == 0 {
+		o.HotCacheTTL = defaultHotCacheTTL
+	}
+	switch {
+	case o.Shards < 0:
+		return o, fmt.Errorf("shard count must be positive, got %d", o.Shards)
+	case o.TTL < 0:
+		return o, fmt.Errorf("ttl must not be negative, got %v", o.TTL)
+	case o.HotCacheBytes < 0:
+		return o, fmt.Errorf("hot cache size must not be negative, got %d", o.HotCacheBytes)
+	case o.HotCacheTTL < 0:
+		return o, fmt.Errorf("hot cache ttl must not be negative, got %v", o.HotCacheTTL)
+	case o.LoadTimeout < 0:
+		return o, fmt.Errorf("load timeout must not be negative, got %v", o.LoadTimeout)
+	}
+	return o, nil
+}
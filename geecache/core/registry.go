@@ -0,0 +1,81 @@
+package core
+
+import (
+	"GeeCache/geecache/interfaces"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// Registry 按名字保存一组 Group。每个节点拥有自己的 Registry，
+// 同一进程中可以运行多个互不影响的节点。
+type Registry struct {
+	mu     sync.RWMutex
+	groups map[string]*Group
+}
+
+// DefaultRegistry 是包级函数 NewGroup、GetGroup、DeleteGroup、ListGroups 使用的全局 Registry
+var DefaultRegistry = NewRegistry()
+
+// NewRegistry 创建一个空的 Registry
+func NewRegistry() *Registry {
+	return &Registry{groups: make(map[string]*Group)}
+}
+
+// NewGroup 创建 Group 并注册到 r 中，同名的 Group 会被替换。参数不合法时 panic。
+func (r *Registry) NewGroup(name string, cacheBytes int64, getter interfaces.Getter, algorithm string) *Group {
+	g, err := r.NewGroupWithOptions(name, cacheBytes, getter, WithPolicy(algorithm))
+	if err != nil {
+		panic(err)
+	}
+	return g
+}
+
+// NewGroupWithOptions 按 opts 创建 Group 并注册到 r 中，配置不合法时返回错误。
+// 同名的 Group 会被替换，被替换的 Group 与 DeleteGroup 一样停止并释放共享的内存预算。
+func (r *Registry) NewGroupWithOptions(name string, cacheBytes int64, getter interfaces.Getter, opts ...GroupOption) (*Group, error) {
+	g, err := newGroup(name, cacheBytes, getter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("group %q: %w", name, err)
+	}
+	r.mu.Lock()
+	old, replaced := r.groups[name]
+	r.groups[name] = g
+	r.mu.Unlock()
+	if replaced {
+		old.stop()
+	}
+	return g, nil
+}
+
+// GetGroup 返回名为 name 的 Group，不存在时返回 nil
+func (r *Registry) GetGroup(name string) *Group {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.groups[name]
+}
+
+// DeleteGroup 删除名为 name 的 Group 并停止它的后台清理协程，返回该 Group 是否存在。
+// 已经持有该 Group 的调用方仍然可以继续使用它，但其他节点无法再通过 gRPC 访问。
+func (r *Registry) DeleteGroup(name string) bool {
+	r.mu.Lock()
+	g, ok := r.groups[name]
+	delete(r.groups, name)
+	r.mu.Unlock()
+	if ok {
+		g.stop()
+	}
+	return ok
+}
+
+// ListGroups 返回所有 Group 的名字，按字典序排列
+func (r *Registry) ListGroups() []string {
+	r.mu.RLock()
+	names := make([]string, 0, len(r.groups))
+	for name := range r.groups {
+		names = append(names, name)
+	}
+	r.mu.RUnlock()
+	slices.Sort(names)
+	return names
+}
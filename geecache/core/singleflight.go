@@ -2,13 +2,18 @@
 /*避免同一个 key 被多个并发请求同时访问，减少对后端数据源（如数据库、外部API等）的重复请求。*/
 package core
 
-import "sync"
+import (
+	"context"
+	"sync"
+)
 
 // call 代表正在进行中，或已经结束的请求
 type call struct {
-	wg  sync.WaitGroup // 使用 sync.WaitGroup 锁避免重入
-	val interface{}
-	err error
+	done    chan struct{} // 请求结束时关闭，等待者可以同时监听 ctx.Done()
+	val     interface{}
+	err     error
+	waiters int                // 仍在等待结果的调用方数，受 RequestGroup.mu 保护
+	cancel  context.CancelFunc // 取消 fn 的 ctx，所有调用方都放弃等待时调用
 }
 
 // 管理不同 key 的请求(call)
@@ -18,26 +23,58 @@ type RequestGroup struct {
 }
 
 func (g *RequestGroup) Do(key string, fn func() (interface{}, error)) (interface{}, error) {
+	v, err, _ := g.DoContext(context.Background(), key, func(context.Context) (interface{}, error) {
+		return fn()
+	})
+	return v, err
+}
+
+// DoContext 与 Do 相同，但每个调用方只等待到自己的 ctx 超时或取消，此时立即返回 ctx.Err()。
+// shared 为 true 表示本次调用共享了其他调用方发起的 fn。
+//
+// fn 在单独的协程中执行，传给 fn 的 ctx 保留第一个调用方 ctx 中的值，但不随它取消：
+// 否则第一个调用方放弃等待时，所有共享结果的调用方都会收到它的错误。
+// 所有调用方都放弃等待时才取消 fn 的 ctx，之后的调用方会重新执行 fn。
+func (g *RequestGroup) DoContext(ctx context.Context, key string, fn func(context.Context) (interface{}, error)) (v interface{}, err error, shared bool) {
 	g.mu.Lock()
 	if g.m == nil {
 		g.m = make(map[string]*call)
 	}
-	if c, ok := g.m[key]; ok {
-		g.mu.Unlock()
-		c.wg.Wait()
-		return c.val, c.err
+	c, shared := g.m[key]
+	if !shared {
+		fnCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		c = &call{done: make(chan struct{}), cancel: cancel}
+		g.m[key] = c
+		go g.run(fnCtx, key, c, fn)
 	}
-	c := new(call)
-	c.wg.Add(1)
-	g.m[key] = c
+	c.waiters++
 	g.mu.Unlock()
 
-	c.val, c.err = fn()
-	c.wg.Done()
+	select {
+	case <-c.done:
+		return c.val, c.err, shared
+	case <-ctx.Done():
+		g.mu.Lock()
+		if c.waiters--; c.waiters == 0 {
+			c.cancel()
+			if g.m[key] == c {
+				delete(g.m, key)
+			}
+		}
+		g.mu.Unlock()
+		return nil, ctx.Err(), shared
+	}
+}
+
+// run 执行 fn 并把结果交给所有等待的调用方
+func (g *RequestGroup) run(ctx context.Context, key string, c *call, fn func(context.Context) (interface{}, error)) {
+	defer c.cancel()
+	c.val, c.err = fn(ctx)
 
 	g.mu.Lock()
-	delete(g.m, key)
+	if g.m[key] == c {
+		delete(g.m, key)
+	}
 	g.mu.Unlock()
-
-	return c.val, c.err
+	close(c.done)
 }
@@ -0,0 +1,48 @@
+package core
+
+import (
+	"GeeCache/geecache/cache"
+	"sync/atomic"
+)
+
+// GroupStats 是 Group 的统计快照
+type GroupStats struct {
+	Gets            int64 // Get 调用次数
+	Hits            int64 // mainCache 或 hotCache 命中次数
+	Misses          int64 // 两级缓存都未命中、需要加载的次数
+	PeerLoads       int64 // 从其他节点成功加载的次数
+	PeerErrors      int64 // 从其他节点加载失败的次数
+	LocalLoads      int64 // 调用 getter 成功加载的次数
+	LocalLoadErrors int64 // 调用 getter 失败的次数
+	Dedups          int64 // 通过 singleflight 共享其他请求加载结果的次数
+	MainCache       cache.Stats
+	HotCache        cache.Stats
+}
+
+// groupCounters 是 Group 读取路径上的原子计数器
+type groupCounters struct {
+	gets            atomic.Int64
+	hits            atomic.Int64
+	misses          atomic.Int64
+	peerLoads       atomic.Int64
+	peerErrors      atomic.Int64
+	localLoads      atomic.Int64
+	localLoadErrors atomic.Int64
+	dedups          atomic.Int64
+}
+
+// Stats 返回 Group 的统计快照。各个计数器分别读取，快照之间不保证严格一致。
+func (g *Group) Stats() GroupStats {
+	return GroupStats{
+		Gets:            g.stats.gets.Load(),
+		Hits:            g.stats.hits.Load(),
+		Misses:          g.stats.misses.Load(),
+		PeerLoads:       g.stats.peerLoads.Load(),
+		PeerErrors:      g.stats.peerErrors.Load(),
+		LocalLoads:      g.stats.localLoads.Load(),
+		LocalLoadErrors: g.stats.localLoadErrors.Load(),
+		Dedups:          g.stats.dedups.Load(),
+		MainCache:       g.maincache.Stats(),
+		HotCache:        g.hotcache.Stats(),
+	}
+}
@@ -0,0 +1,82 @@
+package data
+
+import (
+	"hash/fnv"
+	"math"
+)
+
+// A CountMinSketch estimates how often each key has been seen using a fixed
+// amount of memory. 估计值只会偏大不会偏小。
+// It is not safe for concurrent access.
+type CountMinSketch struct {
+	width    uint64
+	depth    int
+	counters [][]uint32
+}
+
+// NewCountMinSketch 创建一个 depth 行、每行 width 个计数器的 Count-Min Sketch
+func NewCountMinSketch(width, depth int) *CountMinSketch {
+	if width < 1 {
+		width = 1
+	}
+	if depth < 1 {
+		depth = 1
+	}
+	counters := make([][]uint32, depth)
+	for i := range counters {
+		counters[i] = make([]uint32, width)
+	}
+	return &CountMinSketch{width: uint64(width), depth: depth, counters: counters}
+}
+
+// hashes 使用双重哈希 h1 + i*h2 为每一行生成下标
+func (s *CountMinSketch) hashes(key string) (uint64, uint64) {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	sum := h.Sum64()
+	return sum, (sum >> 32) | 1
+}
+
+// Increment 将 key 的计数加一，并返回加一后的估计值
+func (s *CountMinSketch) Increment(key string) uint32 {
+	h1, h2 := s.hashes(key)
+	min := uint32(math.MaxUint32)
+	for i := 0; i < s.depth; i++ {
+		idx := (h1 + uint64(i)*h2) % s.width
+		if s.counters[i][idx] < math.MaxUint32 {
+			s.counters[i][idx]++
+		}
+		if s.counters[i][idx] < min {
+			min = s.counters[i][idx]
+		}
+	}
+	return min
+}
+
+// Estimate 返回 key 的估计计数
+func (s *CountMinSketch) Estimate(key string) uint32 {
+	h1, h2 := s.hashes(key)
+	min := uint32(math.MaxUint32)
+	for i := 0; i < s.depth; i++ {
+		if c := s.counters[i][(h1+uint64(i)*h2)%s.width]; c < min {
+			min = c
+		}
+	}
+	return min
+}
+
+// Halve 将所有计数减半，使旧的访问记录逐渐衰减
+func (s *CountMinSketch) Halve() {
+	for _, row := range s.counters {
+		for j := range row {
+			row[j] >>= 1
+		}
+	}
+}
+
+// Reset 清空所有计数
+func (s *CountMinSketch) Reset() {
+	for _, row := range s.counters {
+		clear(row)
+	}
+}
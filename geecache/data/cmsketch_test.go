@@ -0,0 +1,35 @@
+package data
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestCountMinSketch(t *testing.T) {
+	s := NewCountMinSketch(1024, 4)
+	for i := 0; i < 200; i++ {
+		s.Increment("hot")
+	}
+	for i := 0; i < 1000; i++ {
+		s.Increment("key" + strconv.Itoa(i))
+	}
+
+	// 估计值不会低于真实值
+	if n := s.Estimate("hot"); n < 200 {
+		t.Fatalf("estimate of hot is %d, want >= 200", n)
+	}
+	if n := s.Estimate("never"); n > 10 {
+		t.Fatalf("estimate of an unseen key is too large: %d", n)
+	}
+
+	// 衰减后计数减半
+	before := s.Estimate("hot")
+	s.Halve()
+	if n := s.Estimate("hot"); n != before/2 {
+		t.Fatalf("estimate after Halve is %d, want %d", n, before/2)
+	}
+	s.Reset()
+	if n := s.Estimate("hot"); n != 0 {
+		t.Fatalf("estimate after Reset is %d", n)
+	}
+}
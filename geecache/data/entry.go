@@ -2,11 +2,15 @@ package data
 
 import (
 	"GeeCache/geecache/common"
+	"container/list"
+	"time"
 )
 
 // entry represents a key-value pair along with its frequency or other metadata.
 type Entry struct {
-	Key       string       // key of the entry
-	Value     common.Value // value of the entry
-	Frequency int          // access frequency of the entry (for LFU)
+	Key       string        // key of the entry
+	Value     common.Value  // value of the entry
+	Frequency int           // access frequency of the entry (for LFU)
+	Expire    time.Time     // expiration time of the entry, zero means never expire
+	Elem      *list.Element // 条目在所属频率链表中的节点，用于 O(1) 地移动和删除（for LFU）
 }
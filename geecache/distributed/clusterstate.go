@@ -0,0 +1,126 @@
+package distributed
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"sync"
+)
+
+// ClusterState 支持的命令
+const (
+	OpAddPeer     = "add_peer"     // 加入节点或更新节点权重
+	OpRemovePeer  = "remove_peer"  // 删除节点
+	OpSetGroup    = "set_group"    // 创建或修改 group 配置
+	OpDeleteGroup = "delete_group" // 删除 group 配置
+)
+
+// GroupConfig 是通过 Raft 在集群内共享的 group 配置
+type GroupConfig struct {
+	CacheBytes int64  `json:"cache_bytes"`
+	Algorithm  string `json:"algorithm"`
+}
+
+// ClusterCommand 是 ClusterState 的一条命令，编码为 JSON 后写入 Raft 日志
+type ClusterCommand struct {
+	Op     string       `json:"op"`
+	Peer   string       `json:"peer,omitempty"`
+	Weight int          `json:"weight,omitempty"`
+	Group  string       `json:"group,omitempty"`
+	Config *GroupConfig `json:"config,omitempty"`
+}
+
+// Encode 把命令编码为 Raft 日志内容
+func (c ClusterCommand) Encode() []byte {
+	b, _ := json.Marshal(c) // 只包含基本类型，不会失败
+	return b
+}
+
+// ClusterState 是保存集群元数据（节点成员及权重、group 配置）的 Raft 状态机
+type ClusterState struct {
+	mu     sync.RWMutex
+	peers  map[string]int
+	groups map[string]GroupConfig
+}
+
+// NewClusterState 创建一个空的集群状态
+func NewClusterState() *ClusterState {
+	return &ClusterState{
+		peers:  make(map[string]int),
+		groups: make(map[string]GroupConfig),
+	}
+}
+
+// Apply 应用一条已提交的 ClusterCommand
+func (s *ClusterState) Apply(command []byte) error {
+	var cmd ClusterCommand
+	if err := json.Unmarshal(command, &cmd); err != nil {
+		return fmt.Errorf("invalid cluster command: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch cmd.Op {
+	case OpAddPeer:
+		if cmd.Peer == "" {
+			return fmt.Errorf("%s: empty peer", cmd.Op)
+		}
+		s.peers[cmd.Peer] = max(cmd.Weight, 1)
+	case OpRemovePeer:
+		delete(s.peers, cmd.Peer)
+	case OpSetGroup:
+		if cmd.Group == "" || cmd.Config == nil {
+			return fmt.Errorf("%s: group name and config are required", cmd.Op)
+		}
+		s.groups[cmd.Group] = *cmd.Config
+	case OpDeleteGroup:
+		delete(s.groups, cmd.Group)
+	default:
+		return fmt.Errorf("unknown cluster command: %q", cmd.Op)
+	}
+	return nil
+}
+
+// Peers 返回节点及其权重的副本
+func (s *ClusterState) Peers() map[string]int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return maps.Clone(s.peers)
+}
+
+// Groups 返回 group 配置的副本
+func (s *ClusterState) Groups() map[string]GroupConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return maps.Clone(s.groups)
+}
+
+// clusterSnapshot 是 ClusterState 快照的编码格式
+type clusterSnapshot struct {
+	Peers  map[string]int         `json:"peers"`
+	Groups map[string]GroupConfig `json:"groups"`
+}
+
+// Snapshot 把节点和 group 配置编码为 JSON
+func (s *ClusterState) Snapshot() ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return json.Marshal(clusterSnapshot{Peers: s.peers, Groups: s.groups})
+}
+
+// Restore 用快照替换全部状态
+func (s *ClusterState) Restore(data []byte) error {
+	snapshot := clusterSnapshot{Peers: make(map[string]int), Groups: make(map[string]GroupConfig)}
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return fmt.Errorf("invalid cluster snapshot: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.peers, s.groups = snapshot.Peers, snapshot.Groups
+	return nil
+}
+
+var (
+	_ StateMachine = (*ClusterState)(nil)
+	_ Snapshotter  = (*ClusterState)(nil)
+)
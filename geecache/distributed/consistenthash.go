@@ -2,6 +2,7 @@ package distributed
 
 import (
 	"hash/crc32"
+	"math"
 	"sort"
 	"strconv"
 )
@@ -15,6 +16,12 @@ type Map struct {
 	replicas int            // 虚拟节点倍数
 	keys     []int          // Sorted ;哈希环
 	hashMap  map[int]string // 虚拟节点与真实节点的映射表:键是虚拟节点的哈希值，值是真实节点的名称。
+	weights  map[string]int // 真实节点的权重，节点的虚拟节点个数为 replicas*weight
+
+	// 有界负载一致性哈希（consistent hashing with bounded loads）
+	loadBound float64          // ε，每个节点的容量为 (1+ε)×平均负载；0 表示不限制
+	loads     map[string]int64 // 每个真实节点当前正在处理的请求数
+	totalLoad int64
 }
 
 // New creates a Map instance
@@ -24,6 +31,8 @@ func New(replicas int, fn Hash) *Map {
 		replicas: replicas,
 		hash:     fn,
 		hashMap:  make(map[int]string),
+		weights:  make(map[string]int),
+		loads:    make(map[string]int64),
 	}
 	if m.hash == nil {
 		m.hash = crc32.ChecksumIEEE
@@ -39,16 +48,43 @@ func New(replicas int, fn Hash) *Map {
 
 func (m *Map) Add(keys ...string) {
 	for _, key := range keys {
-		for i := 0; i < m.replicas; i++ {
-			hash := int(m.hash([]byte(strconv.Itoa(i) + key)))
-			m.keys = append(m.keys, hash)
-			m.hashMap[hash] = key
-		}
+		m.addNode(key, 1)
 	}
 	// 最后一步，环上的哈希值排序。
 	sort.Ints(m.keys)
 }
 
+// AddWeighted 按权重加入节点：权重为 w 的节点拥有 m.replicas*w 个虚拟节点，
+// 因此在环上拥有大约 w 倍的 key。权重小于 1 时按 1 处理。
+// 节点已经在环上时按新的权重重新生成它的虚拟节点，正在处理的请求数保持不变。
+func (m *Map) AddWeighted(weights map[string]int) {
+	for key, weight := range weights {
+		m.addNode(key, weight)
+	}
+	sort.Ints(m.keys)
+}
+
+func (m *Map) addNode(key string, weight int) {
+	if weight < 1 {
+		weight = 1
+	}
+	// 重复加入同一节点时先删除它原有的虚拟节点，否则旧的虚拟节点会留在环上
+	if _, ok := m.weights[key]; ok {
+		m.removeVirtualNodes(map[string]bool{key: true})
+	}
+	m.weights[key] = weight
+	for i := 0; i < m.replicas*weight; i++ {
+		hash := int(m.hash([]byte(strconv.Itoa(i) + key)))
+		m.keys = append(m.keys, hash)
+		m.hashMap[hash] = key
+	}
+}
+
+// Weight 返回节点的权重，节点不在环上时返回 0
+func (m *Map) Weight(node string) int {
+	return m.weights[node]
+}
+
 // Get gets the closest item in the hash to the provided key
 func (m *Map) Get(key string) string {
 	if len(m.keys) == 0 {
@@ -69,15 +105,215 @@ func (m *Map) Get(key string) string {
 
 }
 
+// GetMultipleNodes 沿哈希环顺时针返回 replicas 个不同的真实节点，第一个与 Get 相同。
+// 同一节点的多个虚拟节点只计一次；replicas 超过节点数时返回全部节点，哈希环为空时返回 nil。
 func (m *Map) GetMultipleNodes(key string, replicas int) []string {
+	if replicas > len(m.weights) {
+		replicas = len(m.weights)
+	}
+	if replicas <= 0 || len(m.keys) == 0 {
+		return nil
+	}
 	hash := int(m.hash([]byte(key)))
-	var nodes []string
 	idx := sort.Search(len(m.keys), func(i int) bool {
 		return m.keys[i] >= hash
 	})
 
-	for i := 0; i < replicas; i++ {
-		nodes = append(nodes, m.hashMap[m.keys[(idx+i)%len(m.keys)]])
+	nodes := make([]string, 0, replicas)
+	seen := make(map[string]bool, replicas)
+	for i := 0; i < len(m.keys) && len(nodes) < replicas; i++ {
+		node := m.hashMap[m.keys[(idx+i)%len(m.keys)]]
+		if !seen[node] {
+			seen[node] = true
+			nodes = append(nodes, node)
+		}
 	}
 	return nodes
 }
+
+// Remove removes some nodes and all of their virtual nodes from the hash.
+// 与 Add 对称：删除每个真实节点对应的 m.replicas 个虚拟节点，其余节点的位置不变。
+func (m *Map) Remove(nodes ...string) {
+	removed := make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		removed[node] = true
+		delete(m.weights, node)
+		m.totalLoad -= m.loads[node]
+		delete(m.loads, node)
+	}
+	m.removeVirtualNodes(removed)
+}
+
+// removeVirtualNodes 从环上删除 removed 中节点的所有虚拟节点，不改变剩余虚拟节点的顺序
+func (m *Map) removeVirtualNodes(removed map[string]bool) {
+	keys := m.keys[:0]
+	for _, hash := range m.keys {
+		// 哈希冲突时同一个位置可能出现多次，映射已被删除的位置同样丢弃
+		if node, ok := m.hashMap[hash]; !ok || removed[node] {
+			delete(m.hashMap, hash)
+			continue
+		}
+		keys = append(keys, hash)
+	}
+	m.keys = keys
+}
+
+// Nodes 返回环上所有真实节点的名称（无序）
+func (m *Map) Nodes() []string {
+	seen := make(map[string]bool)
+	var nodes []string
+	for _, node := range m.hashMap {
+		if !seen[node] {
+			seen[node] = true
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// Clone 返回 Map 的一份拷贝，用于在修改节点前保存旧的哈希环
+func (m *Map) Clone() *Map {
+	c := &Map{
+		hash:     m.hash,
+		replicas: m.replicas,
+		keys:     append([]int(nil), m.keys...),
+		hashMap:  make(map[int]string, len(m.hashMap)),
+		weights:  make(map[string]int, len(m.weights)),
+		loads:    make(map[string]int64, len(m.loads)),
+
+		loadBound: m.loadBound,
+		totalLoad: m.totalLoad,
+	}
+	for k, v := range m.loads {
+		c.loads[k] = v
+	}
+	for k, v := range m.hashMap {
+		c.hashMap[k] = v
+	}
+	for k, v := range m.weights {
+		c.weights[k] = v
+	}
+	return c
+}
+
+// KeyRange 表示哈希环上 (Start, End] 的一段哈希值区间从 From 节点迁移到了 To 节点。
+// Start >= End 时区间跨过了环的零点。From 或 To 为空表示迁移前或迁移后环上没有节点。
+type KeyRange struct {
+	Start, End uint32
+	From, To   string
+}
+
+// owner 返回哈希值 hash 在环上归属的真实节点
+func (m *Map) owner(hash int) string {
+	if len(m.keys) == 0 {
+		return ""
+	}
+	idx := sort.SearchInts(m.keys, hash)
+	return m.hashMap[m.keys[idx%len(m.keys)]]
+}
+
+// MovedRanges 比较 old 和 new 两个哈希环，返回归属发生变化的区间，相邻且迁移方向相同的区间会被合并。
+func MovedRanges(old, new *Map) []KeyRange {
+	// 两个环上所有虚拟节点的位置把哈希空间切分成若干区间，每个区间在新旧环上各自只有一个归属节点
+	bounds := append(append([]int(nil), old.keys...), new.keys...)
+	sort.Ints(bounds)
+	n := 0
+	for i, b := range bounds {
+		if i == 0 || b != bounds[i-1] {
+			bounds[n] = b
+			n++
+		}
+	}
+	bounds = bounds[:n]
+	if n == 0 {
+		return nil
+	}
+
+	var ranges []KeyRange
+	for i, end := range bounds {
+		// 第一个区间跨过零点，起点是最后一个边界
+		start := bounds[(i+n-1)%n]
+		from, to := old.owner(end), new.owner(end)
+		if from == to {
+			continue
+		}
+		if last := len(ranges) - 1; last >= 0 && ranges[last].End == uint32(start) &&
+			ranges[last].From == from && ranges[last].To == to {
+			ranges[last].End = uint32(end)
+			continue
+		}
+		ranges = append(ranges, KeyRange{Start: uint32(start), End: uint32(end), From: from, To: to})
+	}
+	return ranges
+}
+
+// SetLoadBound 开启有界负载模式：每个节点最多承担 (1+epsilon)×平均值 的请求，
+// epsilon <= 0 时关闭该模式。
+func (m *Map) SetLoadBound(epsilon float64) {
+	if epsilon < 0 {
+		epsilon = 0
+	}
+	m.loadBound = epsilon
+}
+
+// LoadBound 返回当前的 ε，0 表示未开启有界负载模式
+func (m *Map) LoadBound() float64 {
+	return m.loadBound
+}
+
+// capacity 返回再增加一个请求后节点 node 允许承担的最大请求数，
+// 平均负载按节点权重分摊，权重大的节点容量也更大。
+func (m *Map) capacity(node string) int64 {
+	totalWeight := 0
+	for _, w := range m.weights {
+		totalWeight += w
+	}
+	if totalWeight == 0 {
+		return 0
+	}
+	avg := float64(m.totalLoad+1) * float64(m.weights[node]) / float64(totalWeight)
+	return int64(math.Ceil(avg * (1 + m.loadBound)))
+}
+
+// GetLeast 与 Get 相同，但在有界负载模式下，若拥有 key 的节点已达到容量上限，
+// 则沿着哈希环顺时针寻找第一个未超载的节点。未开启该模式时等价于 Get。
+func (m *Map) GetLeast(key string) string {
+	if len(m.keys) == 0 || m.loadBound <= 0 {
+		return m.Get(key)
+	}
+	hash := int(m.hash([]byte(key)))
+	idx := sort.Search(len(m.keys), func(i int) bool {
+		return m.keys[i] >= hash
+	})
+	for i := 0; i < len(m.keys); i++ {
+		node := m.hashMap[m.keys[(idx+i)%len(m.keys)]]
+		if m.loads[node]+1 <= m.capacity(node) {
+			return node
+		}
+	}
+	// 容量至少为平均值，总能找到未超载的节点；这里只是兜底
+	return m.hashMap[m.keys[idx%len(m.keys)]]
+}
+
+// Inc 记录节点开始处理一个请求
+func (m *Map) Inc(node string) {
+	if _, ok := m.weights[node]; !ok {
+		return
+	}
+	m.loads[node]++
+	m.totalLoad++
+}
+
+// Done 记录节点完成了一个请求
+func (m *Map) Done(node string) {
+	if m.loads[node] <= 0 {
+		return
+	}
+	m.loads[node]--
+	m.totalLoad--
+}
+
+// Load 返回节点当前正在处理的请求数
+func (m *Map) Load(node string) int64 {
+	return m.loads[node]
+}
@@ -1,6 +1,7 @@
 package distributed
 
 import (
+	"reflect"
 	"strconv"
 	"testing"
 )
@@ -43,3 +44,184 @@ func TestHashing(t *testing.T) {
 	}
 
 }
+
+func TestRemoveAndMovedRanges(t *testing.T) {
+	hash := New(3, func(key []byte) uint32 {
+		i, _ := strconv.Atoi(string(key))
+		return uint32(i)
+	})
+
+	// 2, 4, 6, 12, 14, 16, 22, 24, 26
+	hash.Add("6", "4", "2")
+	old := hash.Clone()
+
+	// 删除 4 之后，原先归属 4 的 (2,4]、(12,14]、(22,24] 都迁移到 6
+	hash.Remove("4")
+	testCases := map[string]string{
+		"3":  "6",
+		"13": "6",
+		"23": "6",
+		"11": "2",
+		"27": "2",
+	}
+	for k, v := range testCases {
+		if hash.Get(k) != v {
+			t.Errorf("Asking for %s, should have yielded %s", k, v)
+		}
+	}
+
+	moved := MovedRanges(old, hash)
+	expect := []KeyRange{
+		{Start: 2, End: 4, From: "4", To: "6"},
+		{Start: 12, End: 14, From: "4", To: "6"},
+		{Start: 22, End: 24, From: "4", To: "6"},
+	}
+	if !reflect.DeepEqual(moved, expect) {
+		t.Errorf("moved ranges = %+v, want %+v", moved, expect)
+	}
+
+	// 旧环不受影响，且反向比较得到反向迁移
+	if old.Get("3") != "4" {
+		t.Errorf("Clone should not share state with the original map")
+	}
+	if back := MovedRanges(hash, old); len(back) != 3 || back[0].From != "6" || back[0].To != "4" {
+		t.Errorf("reverse moved ranges = %+v", back)
+	}
+}
+
+// 权重为 w 的节点拥有 w 倍的虚拟节点，因此拥有更多的 key
+func TestWeightedNodes(t *testing.T) {
+	hash := New(3, func(key []byte) uint32 {
+		i, _ := strconv.Atoi(string(key))
+		return uint32(i)
+	})
+
+	// "2" 的权重为 2，虚拟节点为 2, 12, 22, 32, 42, 52；"4" 的虚拟节点为 4, 14, 24
+	hash.AddWeighted(map[string]int{"2": 2, "4": 1})
+	if hash.Weight("2") != 2 || hash.Weight("4") != 1 {
+		t.Fatalf("unexpected weights %d %d", hash.Weight("2"), hash.Weight("4"))
+	}
+	testCases := map[string]string{
+		"3":  "4",
+		"25": "2",
+		"33": "2",
+		"53": "2",
+		"13": "4",
+	}
+	for k, v := range testCases {
+		if hash.Get(k) != v {
+			t.Errorf("Asking for %s, should have yielded %s", k, v)
+		}
+	}
+
+	// 删除后权重也被清除
+	hash.Remove("2")
+	if hash.Weight("2") != 0 || hash.Get("33") != "4" {
+		t.Errorf("node 2 should be fully removed")
+	}
+}
+
+// 使用默认哈希函数时，权重为 3 的节点应拥有明显更多的 key
+func TestWeightedDistribution(t *testing.T) {
+	hash := New(50, nil)
+	hash.AddWeighted(map[string]int{"localhost:8001": 3, "localhost:8002": 1})
+
+	count := 0
+	const n = 20000
+	for i := 0; i < n; i++ {
+		if hash.Get("key"+strconv.Itoa(i)) == "localhost:8001" {
+			count++
+		}
+	}
+	if share := float64(count) / n; share < 0.6 || share > 0.9 {
+		t.Errorf("weighted node owns %.2f of the keys, want about 0.75", share)
+	}
+}
+
+// 有界负载模式下，拥有者超载时 key 会落到环上的下一个节点
+func TestBoundedLoad(t *testing.T) {
+	hash := New(3, func(key []byte) uint32 {
+		i, _ := strconv.Atoi(string(key))
+		return uint32(i)
+	})
+	// 2, 4, 6, 12, 14, 16, 22, 24, 26
+	hash.Add("6", "4", "2")
+
+	// 未开启时等价于 Get
+	hash.Inc("2")
+	hash.Inc("2")
+	if hash.GetLeast("11") != "2" {
+		t.Fatalf("GetLeast should equal Get when bounded load is disabled")
+	}
+
+	// ε=0.25：加上新请求共 3 个请求，平均每个节点 1 个，容量为 ceil(1*1.25)=2，
+	// "2" 已有 2 个请求，顺时针找到 "4"
+	hash.SetLoadBound(0.25)
+	if got := hash.GetLeast("11"); got != "4" {
+		t.Fatalf("expected overloaded node 2 to be skipped, got %s", got)
+	}
+	hash.Done("2")
+	hash.Done("2")
+	if got := hash.GetLeast("11"); got != "2" {
+		t.Fatalf("expected node 2 after its load dropped, got %s", got)
+	}
+
+	// 删除节点时同时清除它的负载
+	hash.Remove("2")
+	if hash.Load("2") != 0 {
+		t.Fatalf("load of a removed node should be cleared")
+	}
+}
+
+func TestGetMultipleNodesDistinct(t *testing.T) {
+	hash := New(3, func(key []byte) uint32 {
+		i, _ := strconv.Atoi(string(key))
+		return uint32(i)
+	})
+	if nodes := hash.GetMultipleNodes("1", 2); nodes != nil {
+		t.Fatalf("empty ring returned %v", nodes)
+	}
+
+	// 虚拟节点：2, 4, 6, 12, 14, 16, 22, 24, 26，"11" 之后依次是 12(2)、14(4)、16(6)
+	hash.Add("6", "4", "2")
+	if nodes := hash.GetMultipleNodes("11", 2); !reflect.DeepEqual(nodes, []string{"2", "4"}) {
+		t.Fatalf("expected [2 4], got %v", nodes)
+	}
+	// 请求数超过节点数时返回全部节点，且不重复
+	if nodes := hash.GetMultipleNodes("25", 5); !reflect.DeepEqual(nodes, []string{"6", "2", "4"}) {
+		t.Fatalf("expected [6 2 4], got %v", nodes)
+	}
+	if nodes := ReplicaNodes(hash, "11", 2, "2"); !reflect.DeepEqual(nodes, []string{"4", "6"}) {
+		t.Fatalf("expected [4 6] when skipping 2, got %v", nodes)
+	}
+}
+
+func TestAddExistingNode(t *testing.T) {
+	hash := New(3, func(key []byte) uint32 {
+		i, _ := strconv.Atoi(string(key))
+		return uint32(i)
+	})
+
+	// 重复加入不会产生第二组虚拟节点
+	hash.Add("2", "4")
+	hash.Add("2")
+	if want := []int{2, 4, 12, 14, 22, 24}; !reflect.DeepEqual(hash.keys, want) {
+		t.Errorf("keys after adding 2 twice = %v, want %v", hash.keys, want)
+	}
+
+	// 权重从 2 降到 1 后，32、42、52 三个虚拟节点应被删除
+	hash.AddWeighted(map[string]int{"2": 2})
+	hash.AddWeighted(map[string]int{"2": 1})
+	if hash.Weight("2") != 1 {
+		t.Errorf("weight of 2 = %d, want 1", hash.Weight("2"))
+	}
+	if want := []int{2, 4, 12, 14, 22, 24}; !reflect.DeepEqual(hash.keys, want) {
+		t.Errorf("keys after reweighting 2 down = %v, want %v", hash.keys, want)
+	}
+	if got := hash.Get("33"); got != "2" {
+		t.Errorf("Asking for 33, should have yielded 2, got %s", got)
+	}
+	if len(hash.hashMap) != len(hash.keys) {
+		t.Errorf("hashMap has %d virtual nodes, ring has %d", len(hash.hashMap), len(hash.keys))
+	}
+}
@@ -4,12 +4,18 @@ import (
 	"GeeCache/geecache/core"
 	"GeeCache/geecache/geecachepb"
 	"GeeCache/geecache/interfaces"
+	"GeeCache/geecache/logging"
+	"GeeCache/geecache/metrics"
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"math/rand"
 	"net"
+	"os"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
@@ -17,59 +23,359 @@ import (
 
 /*为 HTTPPool 添加节点选择的功能*/
 const (
-	defaultBasePath = "/_geeccache/"
-	defaultReplicas = 50
+	defaultBasePath   = "/_geeccache/"
+	defaultReplicas   = 50
+	defaultRPCTimeout = 3 * time.Second // ctx 没有截止时间时，节点间调用的默认超时
 )
 
 // GRPCPool 用于管理 gRPC 节点池，并提供通信接口
 type GRPCPool struct {
 	self        string                 //  保持为字符串类型，可以代表节点的主机地址、域名或其他唯一标识符
 	mu          sync.Mutex             // guards peer and grpcGetters
-	peers       *Map                   // 一致性哈希映射，选择节点
+	peers       PeerSelector           // 选择节点的算法，默认为一致性哈希环
 	grpcClients map[string]*grpcClient // 每个节点对应的 gRPC 客户端
+	inflight    map[string]int64       // 发往每个节点且尚未完成的调用数
+	raft        *Raft                  // 第一次 Set 或 StartRaft 时启动，之后的成员变化不再重复启动
+	cluster     *ClusterState          // Raft 复制的集群元数据，记录节点权重
+	dialOpts    []grpc.DialOption      // 连接其他节点时追加的选项，Raft 也使用这些选项
+	raftConfig  RaftConfig             // 启动 Raft 时使用的超时、存储等配置
+	transport   *GRPCTransport         // 节点池为 Raft 创建的传输层，Close 时关闭
+	logger      *slog.Logger           // 节点池和 Raft 使用的 logger
 }
 
-// NewGRPCPool 初始化一个 gRPC 节点池
+// NewGRPCPool 初始化一个使用一致性哈希环选择节点的 gRPC 节点池
 func NewGRPCPool(self string) *GRPCPool {
+	return NewGRPCPoolWithSelector(self, New(defaultReplicas, nil))
+}
+
+// NewGRPCPoolWithSelector 初始化一个使用指定算法选择节点的 gRPC 节点池，
+// 例如 NewRendezvous(nil) 或 NewJumpHash(nil)。
+func NewGRPCPoolWithSelector(self string, selector PeerSelector) *GRPCPool {
 	return &GRPCPool{
-		self: self,
+		self:        self,
+		peers:       selector,
+		grpcClients: make(map[string]*grpcClient),
+		inflight:    make(map[string]int64),
+		logger:      logging.Default(),
 	}
 }
 
-// Set 更新节点池中的节点并启动 Raft 算法
-func (p *GRPCPool) Set(peers ...string) {
+// SetLogger 设置节点池使用的 logger，Raft 的配置中没有指定 logger 时也使用它，l 为 nil 时使用 logging.Default()。
+// 应在 Set 或 StartRaft 之前调用。
+func (p *GRPCPool) SetLogger(l *slog.Logger) {
+	if l == nil {
+		l = logging.Default()
+	}
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	p.logger = l
+}
 
-	// 一致性哈希映射
-	p.peers = New(defaultReplicas, nil)
-	p.peers.Add(peers...)
-	p.grpcClients = make(map[string]*grpcClient, len(peers))
+// Set 把节点池中的节点替换为 peers（权重均为 1），第一次调用时以 peers 为初始成员启动 Raft 算法。
+// 已有节点的连接会被复用，不再属于节点池的连接会被关闭。返回归属发生变化的哈希区间。
+func (p *GRPCPool) Set(peers ...string) []KeyRange {
+	weights := make(map[string]int, len(peers))
+	for _, peer := range peers {
+		weights[peer] = 1
+	}
+	return p.SetWeighted(weights)
+}
+
+// SetWeighted 与 Set 相同，但每个节点带有权重（容量），权重越大的节点拥有越多的 key。
+// 已有节点的权重发生变化时只调整哈希环，不重新建立连接。
+func (p *GRPCPool) SetWeighted(weights map[string]int) []KeyRange {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 
 	// 启动 Raft 算法
-	raft := NewRaft(p.self, peers)
-	go raft.Start()
+	if p.raft == nil {
+		peers := make([]string, 0, len(weights))
+		for peer := range weights {
+			peers = append(peers, peer)
+		}
+		p.startRaftLocked(peers)
+	}
+
+	var removed []string
+	for peer := range p.grpcClients {
+		if _, ok := weights[peer]; !ok {
+			removed = append(removed, peer)
+		}
+	}
+	added := make(map[string]int)
+	for peer, weight := range weights {
+		if _, ok := p.grpcClients[peer]; !ok || p.weightChanged(peer, weight) {
+			added[peer] = weight
+		}
+	}
+	return p.updateLocked(added, removed)
+}
+
+// Raft 返回节点池的 Raft 节点，尚未启动时返回 nil
+func (p *GRPCPool) Raft() *Raft {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.raft
+}
 
-	// 为每个 peer 创建 gRPC 连接
+// StartRaft 以 bootstrap 为初始成员启动 Raft 算法并返回 Raft 节点，已经启动时直接返回。
+// bootstrap 为空时本节点等待通过 JoinCluster 加入已有的集群。
+// 之后每次成员变更被应用时，节点池都会按新的成员列表重新配置。
+func (p *GRPCPool) StartRaft(bootstrap ...string) *Raft {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.raft == nil {
+		p.startRaftLocked(bootstrap)
+	}
+	return p.raft
+}
+
+// SetDialOptions 设置连接其他节点时追加的 gRPC 选项，只影响之后建立的连接，应在 Set 之前调用
+func (p *GRPCPool) SetDialOptions(opts ...grpc.DialOption) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.dialOpts = opts
+}
+
+// SetRaftConfig 设置启动 Raft 时使用的配置，应在 Set 或 StartRaft 之前调用。
+// Self、Peers、StateMachine 和 OnMembershipChange 由节点池填写，Transport 为空时使用带 dialOpts 的 gRPC 传输层。
+func (p *GRPCPool) SetRaftConfig(cfg RaftConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.raftConfig = cfg
+}
+
+func (p *GRPCPool) startRaftLocked(bootstrap []string) {
+	p.cluster = NewClusterState()
+	cfg := p.raftConfig
+	cfg.Self, cfg.Peers = p.self, bootstrap
+	cfg.StateMachine, cfg.OnMembershipChange = p.cluster, p.applyMembership
+	if cfg.Transport == nil {
+		p.transport = NewGRPCTransport(p.dialOpts...)
+		cfg.Transport = p.transport
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = p.logger
+	}
+	r, err := NewRaftWithConfig(cfg)
+	if err != nil {
+		p.logger.Error("failed to create raft node", "self", p.self, "error", err)
+		os.Exit(1)
+	}
+	p.raft = r
+	p.raft.Start()
+}
+
+// applyMembership 在 Raft 应用成员变更后重新配置节点池。节点权重来自 ClusterState，
+// 没有记录的节点（初始成员）沿用当前权重。
+func (p *GRPCPool) applyMembership(members []string) {
+	recorded := p.cluster.Peers()
+	p.mu.Lock()
+	weights := make(map[string]int, len(members))
+	for _, member := range members {
+		weight := recorded[member]
+		if ws, ok := p.peers.(WeightedSelector); ok && weight == 0 {
+			weight = ws.Weight(member)
+		}
+		weights[member] = max(weight, 1)
+	}
+	p.mu.Unlock()
+	p.SetWeighted(weights)
+}
+
+// JoinCluster 请求 seed 所在的集群把本节点以 weight 的权重加入集群，必要时以加入者身份启动 Raft。
+// 变更写入日志后领导者就开始向本节点复制日志，因此应先用 StartRaft 返回的 Raft 启动本节点的 gRPC 服务。
+func (p *GRPCPool) JoinCluster(ctx context.Context, seed string, weight int) error {
+	return p.StartRaft().Join(ctx, seed, weight)
+}
+
+// Close 停止 Raft 并关闭所有连接
+func (p *GRPCPool) Close() error {
+	// Raft 应用成员变更时会回调节点池，停止时不能持有 p.mu
+	if r := p.Raft(); r != nil {
+		r.Stop()
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var err error
+	if p.transport != nil {
+		err = p.transport.Close()
+	}
+	for peer, client := range p.grpcClients {
+		if cerr := client.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+		delete(p.grpcClients, peer)
+	}
+	return err
+}
+
+// LeaveCluster 把本节点移出集群
+func (p *GRPCPool) LeaveCluster(ctx context.Context) error {
+	r := p.Raft()
+	if r == nil {
+		return errors.New("raft is not running")
+	}
+	_, err := r.RemoveMember(ctx, p.self)
+	return err
+}
+
+// AddPeer 向节点池中加入权重为 1 的节点，只为新节点建立连接。返回迁移到新节点的哈希区间。
+func (p *GRPCPool) AddPeer(peers ...string) []KeyRange {
+	weights := make(map[string]int, len(peers))
 	for _, peer := range peers {
-		conn, err := grpc.Dial(
-			peer,
-			grpc.WithTransportCredentials(insecure.NewCredentials()), // 使用不加密的连接
-		)
+		weights[peer] = 1
+	}
+	return p.AddWeightedPeer(weights)
+}
+
+// AddWeightedPeer 向节点池中加入带权重的节点，已存在的节点只更新权重。
+func (p *GRPCPool) AddWeightedPeer(weights map[string]int) []KeyRange {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	added := make(map[string]int)
+	for peer, weight := range weights {
+		if _, ok := p.grpcClients[peer]; !ok || p.weightChanged(peer, weight) {
+			added[peer] = weight
+		}
+	}
+	return p.updateLocked(added, nil)
+}
+
+// RemovePeer 从节点池中删除节点并关闭与其的连接。返回从被删除节点迁出的哈希区间。
+func (p *GRPCPool) RemovePeer(peers ...string) []KeyRange {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var removed []string
+	for _, peer := range peers {
+		if _, ok := p.grpcClients[peer]; ok {
+			removed = append(removed, peer)
+		}
+	}
+	return p.updateLocked(nil, removed)
+}
+
+// weightChanged 判断已有节点的权重是否需要更新，不支持权重的 PeerSelector 总是返回 false
+func (p *GRPCPool) weightChanged(peer string, weight int) bool {
+	ws, ok := p.peers.(WeightedSelector)
+	return ok && ws.Weight(peer) != max(weight, 1)
+}
+
+// updateLocked 增量修改节点和连接，调用方需持有 p.mu。
+// added 中已存在的节点只会按新权重重新加入，连接保持不变。
+// 只有一致性哈希环 Map 能报告迁移的哈希区间，其他 PeerSelector 返回 nil。
+func (p *GRPCPool) updateLocked(added map[string]int, removed []string) []KeyRange {
+	if len(added) == 0 && len(removed) == 0 {
+		return nil
+	}
+	var old *Map
+	if ring, ok := p.peers.(*Map); ok {
+		old = ring.Clone()
+	}
+
+	for _, peer := range removed {
+		if err := p.grpcClients[peer].Close(); err != nil {
+			p.logger.Warn("failed to close connection to peer", "self", p.self, "peer", peer, "error", err)
+		}
+		delete(p.grpcClients, peer)
+		delete(p.inflight, peer)
+	}
+	p.peers.Remove(removed...)
+
+	// 为每个新 peer 创建 gRPC 连接
+	for peer := range added {
+		if _, ok := p.grpcClients[peer]; ok {
+			p.peers.Remove(peer) // 权重变化，先移出再按新权重加入
+			continue
+		}
+		client, err := NewGRPCClient(peer, p.dialOpts...)
 		if err != nil {
-			log.Fatalf("failed to connect to peer %s: %v", peer, err)
+			p.logger.Error("failed to connect to peer", "self", p.self, "peer", peer, "error", err)
+			os.Exit(1)
 		}
-		client := geecachepb.NewGroupCacheClient(conn)
-		p.grpcClients[peer] = &grpcClient{client: client} // 将 gRPC 客户端封装成 grpcClient
+		client.name, client.pool = peer, p
+		p.grpcClients[peer] = client
+	}
+	if ws, ok := p.peers.(WeightedSelector); ok {
+		ws.AddWeighted(added)
+	} else {
+		for peer := range added {
+			p.peers.Add(peer)
+		}
+	}
+
+	var moved []KeyRange
+	if old != nil {
+		moved = MovedRanges(old, p.peers.(*Map))
+	}
+	p.logger.Info("peers changed", "self", p.self, "added", added, "removed", removed, "moved_ranges", len(moved))
+	return moved
+}
+
+// Peers 返回节点池中的所有节点
+func (p *GRPCPool) Peers() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.peers.Nodes()
+}
+
+// SetBoundedLoad 开启有界负载一致性哈希：拥有 key 的节点正在处理的调用数超过
+// (1+epsilon)×平均值 时，PickPeer 沿哈希环选择下一个节点。epsilon <= 0 时关闭。
+// PeerSelector 不支持有界负载时返回错误。
+func (p *GRPCPool) SetBoundedLoad(epsilon float64) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	lb, ok := p.peers.(LoadBoundedSelector)
+	if !ok {
+		return fmt.Errorf("peer selector %T does not support bounded loads", p.peers)
+	}
+	lb.SetLoadBound(epsilon)
+	return nil
+}
+
+// Load 返回本节点发往 peer 且尚未完成的调用数
+func (p *GRPCPool) Load(peer string) int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.inflight[peer]
+}
+
+// track 记录一次发往 peer 的调用，返回的函数在调用结束时执行
+func (p *GRPCPool) track(peer string) func() {
+	p.mu.Lock()
+	p.inflight[peer]++
+	if lb, ok := p.peers.(LoadBoundedSelector); ok {
+		lb.Inc(peer)
+	}
+	p.mu.Unlock()
+	return func() {
+		p.mu.Lock()
+		if p.inflight[peer] > 0 {
+			p.inflight[peer]--
+		}
+		if lb, ok := p.peers.(LoadBoundedSelector); ok {
+			lb.Done(peer)
+		}
+		p.mu.Unlock()
 	}
 }
 
-// PickPeer 根据 key 选择对应的 peer
+// PickPeer 根据 key 选择对应的 peer，开启有界负载时会跳过已超载的节点
 func (p *GRPCPool) PickPeer(key string) (interfaces.PeerGetter, bool) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
-	if peer := p.peers.Get(key); peer != "" && peer != p.self {
-		log.Printf("Pick peer %s", peer)
+	var peer string
+	if lb, ok := p.peers.(LoadBoundedSelector); ok {
+		peer = lb.GetLeast(key)
+	} else {
+		peer = p.peers.Get(key)
+	}
+	if peer != "" && peer != p.self {
+		if p.logger.Enabled(context.Background(), slog.LevelDebug) {
+			p.logger.Debug("pick peer", "self", p.self, "key", key, "peer", peer)
+		}
 		return p.grpcClients[peer], true // 返回 grpcGetter
 	}
 	return nil, false
@@ -77,39 +383,125 @@ func (p *GRPCPool) PickPeer(key string) (interfaces.PeerGetter, bool) {
 
 // grpcClient 用于从远程节点获取缓存数据
 type grpcClient struct {
+	conn   *grpc.ClientConn            // 底层连接，节点被移除时关闭
 	client geecachepb.GroupCacheClient // gRPC 客户端
+	name   string                      // 远程节点地址
+	pool   *GRPCPool                   // 所属节点池，用于统计正在进行的调用数；可为 nil
+
+	latency *metrics.Histogram // 每次调用的耗时
+	errors  atomic.Int64       // 失败的调用数
+}
+
+// observe 记录一次调用的耗时和结果，与 track 一起在调用开始时 defer
+func (g *grpcClient) observe(start time.Time, err *error) {
+	g.latency.ObserveDuration(time.Since(start))
+	if *err != nil {
+		g.errors.Add(1)
+	}
+}
+
+// String 返回远程节点地址，用于日志
+func (g *grpcClient) String() string {
+	return g.name
+}
+
+// track 在所属节点池中记录一次正在进行的调用
+func (g *grpcClient) track() func() {
+	if g.pool == nil {
+		return func() {}
+	}
+	return g.pool.track(g.name)
+}
+
+// Close 关闭与远程节点的连接
+func (g *grpcClient) Close() error {
+	if g.conn == nil {
+		return nil
+	}
+	return g.conn.Close()
+}
+
+// withDefaultTimeout 为没有截止时间的 ctx 加上默认超时，避免远程节点挂起时调用方永远阻塞。
+// ctx 的截止时间会由 gRPC 通过 grpc-timeout 元数据传递给远程节点。
+func withDefaultTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, defaultRPCTimeout)
 }
 
 // Get 实现 PeerGetter 接口，用于通过 gRPC 获取缓存数据
-func (g *grpcClient) Get(in *geecachepb.Request, out *geecachepb.Response) error {
+func (g *grpcClient) Get(ctx context.Context, in *geecachepb.Request, out *geecachepb.Response) (err error) {
+	defer g.observe(time.Now(), &err)
+	defer g.track()()
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
 	// 使用 g.client 发送 gRPC 请求
-	res, err := g.client.Get(context.Background(), in)
+	res, err := g.client.Get(ctx, in)
 	if err != nil {
-		return fmt.Errorf("failed to get: %v", err)
+		return fmt.Errorf("failed to get: %w", err)
 	}
 	out.Value = res.Value // 将返回的数据赋值给 out: 避免复制包含 sync.Mutex 的结构体，尤其是在并发环境下，应该始终通过指针传递这些结构体。
 	return nil
 }
 
+// Delete 实现 PeerGetter 接口，用于通过 gRPC 删除远程节点上的缓存数据
+func (g *grpcClient) Delete(ctx context.Context, in *geecachepb.Request, out *geecachepb.DeleteResponse) (err error) {
+	defer g.observe(time.Now(), &err)
+	defer g.track()()
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+	res, err := g.client.Delete(ctx, in)
+	if err != nil {
+		return fmt.Errorf("failed to delete: %w", err)
+	}
+	out.Removed = res.Removed
+	return nil
+}
+
+// Push 实现 PeerGetter 接口，把热点数据写入远程节点的 hotCache
+func (g *grpcClient) Push(ctx context.Context, in *geecachepb.PushRequest, out *geecachepb.PushResponse) (err error) {
+	defer g.observe(time.Now(), &err)
+	defer g.track()()
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+	res, err := g.client.Push(ctx, in)
+	if err != nil {
+		return fmt.Errorf("failed to push: %w", err)
+	}
+	out.Accepted = res.Accepted
+	return nil
+}
+
 // 检查 grpcClient 是否实现了 PeerGetter 接口
 var _ interfaces.PeerGetter = (*grpcClient)(nil)
 
 // 实现 gRPC 服务器
 type server struct {
 	geecachepb.UnimplementedGroupCacheServer
+	raft   *Raft         // 为 nil 时不处理 Raft 请求
+	groups GroupResolver // 按名字查找本节点的 Group
 }
 
+// GroupResolver 按名字查找 Group，找不到时返回 nil
+type GroupResolver func(name string) *core.Group
+
 func (s *server) Get(ctx context.Context, req *geecachepb.Request) (*geecachepb.Response, error) {
 	groupName := req.GetGroup()
 	key := req.GetKey()
 
-	group := core.GetGroup(groupName)
+	group := s.groups(groupName)
 	if group == nil {
 		return nil, fmt.Errorf("group not found: %s", groupName)
 	}
 
-	// 获取缓存数据
-	view, err := group.Get(key)
+	// 获取缓存数据，调用方的截止时间随 ctx 传入。
+	// 其他节点的 Group 转发来的请求在本节点处理，不再按哈希环转发
+	get := group.GetContext
+	if req.GetForwarded() {
+		get = group.GetForwarded
+	}
+	view, err := get(ctx, key)
 	if err != nil {
 		return nil, fmt.Errorf("error getting key: %v", err)
 	}
@@ -117,37 +509,113 @@ func (s *server) Get(ctx context.Context, req *geecachepb.Request) (*geecachepb.
 	return &geecachepb.Response{Value: view.ByteSlice()}, nil
 }
 
+// Delete 只删除本节点上的数据，不再向其他节点转发，避免节点之间循环删除
+func (s *server) Delete(ctx context.Context, req *geecachepb.Request) (*geecachepb.DeleteResponse, error) {
+	groupName := req.GetGroup()
+
+	group := s.groups(groupName)
+	if group == nil {
+		return nil, fmt.Errorf("group not found: %s", groupName)
+	}
+	return &geecachepb.DeleteResponse{Removed: group.RemoveLocal(req.GetKey())}, nil
+}
+
+// Push 把其他节点推送来的热点数据写入本节点的 hotCache
+func (s *server) Push(ctx context.Context, req *geecachepb.PushRequest) (*geecachepb.PushResponse, error) {
+	groupName := req.GetGroup()
+
+	group := s.groups(groupName)
+	if group == nil {
+		return nil, fmt.Errorf("group not found: %s", groupName)
+	}
+	group.PopulateHotCache(req.GetKey(), req.GetValue(), time.Duration(req.GetTtlMs())*time.Millisecond)
+	return &geecachepb.PushResponse{Accepted: true}, nil
+}
+
+// RequestVote 把投票请求交给本节点的 Raft 处理
+func (s *server) RequestVote(ctx context.Context, req *geecachepb.RequestVoteRequest) (*geecachepb.RequestVoteResponse, error) {
+	if s.raft == nil {
+		return s.UnimplementedGroupCacheServer.RequestVote(ctx, req)
+	}
+	return s.raft.HandleRequestVote(req), nil
+}
+
+// AppendEntries 把日志复制和心跳请求交给本节点的 Raft 处理
+func (s *server) AppendEntries(ctx context.Context, req *geecachepb.AppendEntriesRequest) (*geecachepb.AppendEntriesResponse, error) {
+	if s.raft == nil {
+		return s.UnimplementedGroupCacheServer.AppendEntries(ctx, req)
+	}
+	return s.raft.HandleAppendEntries(req), nil
+}
+
+// InstallSnapshot 把领导者发来的快照交给本节点的 Raft 处理
+func (s *server) InstallSnapshot(ctx context.Context, req *geecachepb.InstallSnapshotRequest) (*geecachepb.InstallSnapshotResponse, error) {
+	if s.raft == nil {
+		return s.UnimplementedGroupCacheServer.InstallSnapshot(ctx, req)
+	}
+	return s.raft.HandleInstallSnapshot(req), nil
+}
+
+// ChangeMembership 处理加入或离开集群的请求
+func (s *server) ChangeMembership(ctx context.Context, req *geecachepb.MembershipRequest) (*geecachepb.MembershipResponse, error) {
+	if s.raft == nil {
+		return s.UnimplementedGroupCacheServer.ChangeMembership(ctx, req)
+	}
+	return s.raft.HandleChangeMembership(ctx, req)
+}
+
+// NewGRPCServer 创建注册了 GroupCache 服务的 gRPC 服务器，raft 不为 nil 时同时处理 Raft 请求
+func NewGRPCServer(raft *Raft, opts ...grpc.ServerOption) *grpc.Server {
+	return NewGRPCServerWithResolver(raft, core.GetGroup, opts...)
+}
+
+// NewGRPCServerWithResolver 与 NewGRPCServer 相同，但通过 groups 查找 Group，
+// 用于同一进程中运行多个节点、每个节点有自己的 Group 的场景
+func NewGRPCServerWithResolver(raft *Raft, groups GroupResolver, opts ...grpc.ServerOption) *grpc.Server {
+	grpcServer := grpc.NewServer(opts...)
+	geecachepb.RegisterGroupCacheServer(grpcServer, &server{raft: raft, groups: groups})
+	return grpcServer
+}
+
 // 启动 gRPC 服务器
 func StartGRPCServer(addr string) {
+	StartGRPCServerWithRaft(addr, nil)
+}
+
+// StartGRPCServerWithRaft 启动 gRPC 服务器，并把 Raft 请求交给 raft 处理
+func StartGRPCServerWithRaft(addr string, raft *Raft) {
+	logger := logging.Default()
 	lis, err := net.Listen("tcp", addr)
 	if err != nil {
-		log.Fatalf("failed to listen: %v", err)
+		logger.Error("failed to listen", "addr", addr, "error", err)
+		os.Exit(1)
 	}
-	grpcServer := grpc.NewServer()
 
 	// 注册 GroupCache 服务
-	geecachepb.RegisterGroupCacheServer(grpcServer, &server{})
+	grpcServer := NewGRPCServer(raft)
 
-	log.Printf("gRPC server listening at %v", addr)
+	logger.Info("gRPC server listening", "addr", addr)
 	if err := grpcServer.Serve(lis); err != nil {
-		log.Fatalf("failed to serve: %v", err)
+		logger.Error("failed to serve", "addr", addr, "error", err)
+		os.Exit(1)
 	}
 }
 
 // NewGRPCClient 创建 gRPC 客户端并与远程服务器建立连接
-func NewGRPCClient(addr string) (*grpcClient, error) {
+// opts 追加在默认选项之后，例如自定义的拨号函数或拦截器
+func NewGRPCClient(addr string, opts ...grpc.DialOption) (*grpcClient, error) {
 	// 使用 grpc.DialContext，并替代 WithInsecure
 	conn, err := grpc.DialContext(
 		context.Background(),
 		addr,
-		grpc.WithTransportCredentials(insecure.NewCredentials()), // 使用不加密的连接
+		append([]grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}, opts...)..., // 使用不加密的连接
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to server: %v", err)
 	}
 
 	client := geecachepb.NewGroupCacheClient(conn)
-	return &grpcClient{client: client}, nil
+	return &grpcClient{conn: conn, client: client, latency: metrics.NewHistogram()}, nil
 }
 
 // Get 获取缓存数据
@@ -167,9 +635,9 @@ func (p *GRPCPool) GetReplicatedPeers(key string, replicas int) []interfaces.Pee
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	peerNames := p.peers.GetMultipleNodes(key, replicas)
+	// 跳过本节点，避免热点读请求和推送发回给自己
 	var getters []interfaces.PeerGetter
-	for _, peer := range peerNames {
+	for _, peer := range ReplicaNodes(p.peers, key, replicas, p.self) {
 		if client, exists := p.grpcClients[peer]; exists {
 			getters = append(getters, client)
 		}
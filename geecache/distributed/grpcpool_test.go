@@ -1,6 +1,17 @@
 // // grpcpool_test.go
 package distributed_test
 
+import (
+	"GeeCache/geecache/distributed"
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+)
+
 // import (
 // 	"GeeCache/geecache/geecachepb"
 // 	"context"
@@ -121,3 +132,131 @@ package distributed_test
 // 		t.Errorf("Expected %s, got %s", data["Alice"], string(value))
 // 	}
 // }
+
+// 增量加入、删除节点时只修改变化的部分，并报告迁移的哈希区间
+func TestGRPCPoolAddRemovePeer(t *testing.T) {
+	pool := distributed.NewGRPCPool("localhost:50060")
+
+	if moved := pool.AddPeer("localhost:50061", "localhost:50062"); len(moved) == 0 {
+		t.Fatal("adding peers to an empty pool should move key ranges")
+	}
+	if moved := pool.AddPeer("localhost:50061"); moved != nil {
+		t.Fatalf("adding an existing peer should move nothing, got %v", moved)
+	}
+
+	moved := pool.RemovePeer("localhost:50062")
+	if len(moved) == 0 {
+		t.Fatal("removing a peer should move key ranges")
+	}
+	for _, r := range moved {
+		if r.From != "localhost:50062" || r.To != "localhost:50061" {
+			t.Fatalf("unexpected moved range %+v", r)
+		}
+	}
+
+	peers := pool.Peers()
+	sort.Strings(peers)
+	if len(peers) != 1 || peers[0] != "localhost:50061" {
+		t.Fatalf("unexpected peers %v", peers)
+	}
+	if _, ok := pool.PickPeer("Tom"); !ok {
+		t.Fatal("the remaining remote peer should own every key")
+	}
+}
+
+// 修改已有节点的权重只会迁移部分哈希区间，不改变节点列表
+func TestGRPCPoolReweightPeer(t *testing.T) {
+	pool := distributed.NewGRPCPool("localhost:50070")
+	pool.AddPeer("localhost:50071", "localhost:50072")
+
+	moved := pool.AddWeightedPeer(map[string]int{"localhost:50071": 4})
+	if len(moved) == 0 {
+		t.Fatal("increasing a weight should move key ranges")
+	}
+	for _, r := range moved {
+		if r.To != "localhost:50071" {
+			t.Fatalf("keys should only move to the heavier peer, got %+v", r)
+		}
+	}
+	if moved := pool.AddWeightedPeer(map[string]int{"localhost:50071": 4}); moved != nil {
+		t.Fatalf("same weight should move nothing, got %v", moved)
+	}
+	if peers := pool.Peers(); len(peers) != 2 {
+		t.Fatalf("unexpected peers %v", peers)
+	}
+}
+
+// 使用 JumpHash 时，节点加入和删除的顺序不同，只要成员相同，PickPeer 就选出相同的节点
+func TestGRPCPoolJumpHashIsOrderIndependent(t *testing.T) {
+	a := distributed.NewGRPCPoolWithSelector("localhost:50090", distributed.NewJumpHash(nil))
+	a.AddPeer("localhost:50091", "localhost:50092", "localhost:50093", "localhost:50094")
+	b := distributed.NewGRPCPoolWithSelector("localhost:50090", distributed.NewJumpHash(nil))
+	b.AddPeer("localhost:50094", "localhost:50095")
+	b.AddPeer("localhost:50092")
+	b.AddPeer("localhost:50091", "localhost:50093")
+	b.RemovePeer("localhost:50095")
+	t.Cleanup(func() {
+		a.Close()
+		b.Close()
+	})
+
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("key%d", i)
+		pa, _ := a.PickPeer(key)
+		pb, _ := b.PickPeer(key)
+		if fmt.Sprint(pa) != fmt.Sprint(pb) {
+			t.Fatalf("pools disagree on %s: %v vs %v", key, pa, pb)
+		}
+	}
+}
+
+// 新节点通过 Raft 加入集群后，所有节点的节点池都按新的成员列表重新配置
+func TestGRPCPoolJoinCluster(t *testing.T) {
+	seed := startNode(t, func(n *distributed.Node) { n.Pool().Set(n.Addr()) })
+	joiner := startNode(t, func(n *distributed.Node) { n.Pool().StartRaft() })
+
+	// 单节点集群选出领导者之前加入请求会失败，重试直到成功
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for {
+		err := joiner.Pool().JoinCluster(ctx, seed.Addr(), 2)
+		if err == nil {
+			break
+		}
+		if ctx.Err() != nil {
+			t.Fatalf("failed to join the cluster: %v", err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	want := []string{seed.Addr(), joiner.Addr()}
+	sort.Strings(want)
+	for _, n := range []*distributed.Node{seed, joiner} {
+		waitFor(t, 5*time.Second, func() bool {
+			peers := n.Pool().Peers()
+			sort.Strings(peers)
+			return slices.Equal(peers, want)
+		})
+	}
+}
+
+// startNode 在随机端口上启动一个节点，start 在开始提供服务之前启动 Raft。测试结束时停止节点并等待服务退出。
+func startNode(t *testing.T, start func(n *distributed.Node)) *distributed.Node {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := distributed.NewNode(lis.Addr().String())
+	start(n)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		n.Serve(lis)
+	}()
+	t.Cleanup(func() {
+		n.Stop()
+		<-done
+	})
+	return n
+}
@@ -0,0 +1,85 @@
+package distributed
+
+import (
+	"hash/fnv"
+	"slices"
+)
+
+// JumpHash 实现 Jump Consistent Hash（Lamping & Veach）：不需要额外内存，分布非常均匀，
+// 但只能在末尾增删桶。桶号按节点名排序分配，与加入、删除的顺序无关，
+// 因此成员相同的节点总是把 key 分给同一个节点。
+// 代价是增删一个名字排在中间的节点时，排在它之后的节点的桶号都会改变，迁移的 key 多于 1/n。
+type JumpHash struct {
+	hash  Hash64
+	nodes []string // 按节点名排序，下标即桶号
+}
+
+// NewJumpHash 创建一个 JumpHash 实例，fn 为 nil 时使用 FNV-1a
+func NewJumpHash(fn Hash64) *JumpHash {
+	if fn == nil {
+		fn = func(data []byte) uint64 {
+			h := fnv.New64a()
+			h.Write(data)
+			return h.Sum64()
+		}
+	}
+	return &JumpHash{hash: fn}
+}
+
+// jump 把 key 映射到 [0, buckets) 中的一个桶
+func jump(key uint64, buckets int) int {
+	var b, j int64 = -1, 0
+	for j < int64(buckets) {
+		b = j
+		key = key*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+	return int(b)
+}
+
+// Add 按节点名的顺序插入节点，名字排在最后的节点追加在末尾
+func (h *JumpHash) Add(nodes ...string) {
+	for _, node := range nodes {
+		if i, ok := slices.BinarySearch(h.nodes, node); !ok {
+			h.nodes = slices.Insert(h.nodes, i, node)
+		}
+	}
+}
+
+// Remove 删除节点，排在它之后的节点依次前移一个桶
+func (h *JumpHash) Remove(nodes ...string) {
+	for _, node := range nodes {
+		if i, ok := slices.BinarySearch(h.nodes, node); ok {
+			h.nodes = slices.Delete(h.nodes, i, i+1)
+		}
+	}
+}
+
+// Get 返回 key 所在桶对应的节点
+func (h *JumpHash) Get(key string) string {
+	if len(h.nodes) == 0 {
+		return ""
+	}
+	return h.nodes[jump(h.hash([]byte(key)), len(h.nodes))]
+}
+
+// GetMultipleNodes 从 key 所在的桶开始，依次返回后续桶上的 n 个不同节点
+func (h *JumpHash) GetMultipleNodes(key string, n int) []string {
+	if n > len(h.nodes) {
+		n = len(h.nodes)
+	}
+	if n <= 0 {
+		return nil
+	}
+	start := jump(h.hash([]byte(key)), len(h.nodes))
+	nodes := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		nodes = append(nodes, h.nodes[(start+i)%len(h.nodes)])
+	}
+	return nodes
+}
+
+// Nodes 返回所有节点，按节点名排序，顺序即桶号
+func (h *JumpHash) Nodes() []string {
+	return append([]string(nil), h.nodes...)
+}
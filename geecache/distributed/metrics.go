@@ -0,0 +1,62 @@
+package distributed
+
+import (
+	"GeeCache/geecache/metrics"
+	"slices"
+	"strings"
+)
+
+// Collect 实现 metrics.Collector，导出哈希环的成员数、发往每个节点的调用耗时和错误数，
+// 以及 Raft 的状态
+func (p *GRPCPool) Collect(w *metrics.Writer) {
+	p.mu.Lock()
+	members := len(p.grpcClients)
+	clients := make([]*grpcClient, 0, len(p.grpcClients))
+	for _, c := range p.grpcClients {
+		clients = append(clients, c)
+	}
+	inflight := make(map[string]int64, len(p.inflight))
+	for peer, n := range p.inflight {
+		inflight[peer] = n
+	}
+	raft := p.raft
+	p.mu.Unlock()
+	slices.SortFunc(clients, func(a, b *grpcClient) int { return strings.Compare(a.name, b.name) })
+
+	self := []string{"self", p.self}
+	w.Gauge("geecache_ring_members", "Number of peers in the hash ring.", float64(members), self...)
+	for _, c := range clients {
+		labels := []string{"self", p.self, "peer", c.name}
+		w.Histogram("geecache_peer_rpc_duration_seconds", "Latency of Get, Delete and Push calls to a peer.", c.latency, labels...)
+		w.Counter("geecache_peer_rpc_errors_total", "Number of failed calls to a peer.", float64(c.errors.Load()), labels...)
+		w.Gauge("geecache_peer_inflight", "Calls to a peer that have not completed.", float64(inflight[c.name]), labels...)
+	}
+	if raft != nil {
+		raft.Collect(w)
+	}
+}
+
+// Collect 实现 metrics.Collector，导出任期、角色、提交索引和成员数
+func (r *Raft) Collect(w *metrics.Writer) {
+	r.mu.Lock()
+	term, state, commit, members := r.currentTerm, r.state, r.commitIndex, len(r.peers)
+	r.mu.Unlock()
+
+	self := []string{"self", r.self}
+	w.Gauge("geecache_raft_term", "Current Raft term.", float64(term), self...)
+	for s, name := range []string{Follower: "follower", Candidate: "candidate", Leader: "leader"} {
+		value := 0.0
+		if int32(s) == state {
+			value = 1
+		}
+		w.Gauge("geecache_raft_state", "Raft role of the node, 1 for the current role.", value, "self", r.self, "state", name)
+	}
+	w.Gauge("geecache_raft_commit_index", "Highest committed Raft log index.", float64(commit), self...)
+	w.Gauge("geecache_raft_members", "Number of voting members in the Raft configuration.", float64(members), self...)
+}
+
+// Collect 实现 metrics.Collector，导出本节点所有 Group、节点池和 Raft 的指标
+func (n *Node) Collect(w *metrics.Writer) {
+	n.registry.Collect(w)
+	n.pool.Collect(w)
+}
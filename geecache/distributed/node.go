@@ -0,0 +1,153 @@
+package distributed
+
+import (
+	"GeeCache/geecache/core"
+	"GeeCache/geecache/interfaces"
+	"GeeCache/geecache/logging"
+	"log/slog"
+	"net"
+	"sync"
+
+	"google.golang.org/grpc"
+)
+
+// Node 是一个缓存节点，拥有自己的 Group、节点池和 gRPC 服务。
+// 同一进程中可以运行多个 Node，它们的 Group 互不可见。
+// 包级的 NewGRPCServer、StartGRPCServer 仍然使用 core.DefaultRegistry。
+type Node struct {
+	addr     string
+	registry *core.Registry
+	pool     *GRPCPool
+	opts     []grpc.ServerOption
+	logger   *slog.Logger // 为 nil 时使用 logging.Default()
+
+	mu      sync.Mutex
+	server  *grpc.Server
+	stopped bool
+}
+
+// NewNode 创建地址为 addr 的节点，使用一个新的 Registry
+func NewNode(addr string, opts ...grpc.ServerOption) *Node {
+	return NewNodeWithRegistry(addr, core.NewRegistry(), opts...)
+}
+
+// NewNodeWithRegistry 与 NewNode 相同，但在 registry 中保存 Group。
+// 传入 core.DefaultRegistry 时，通过 core.NewGroup 创建的 Group 也能被其他节点访问。
+func NewNodeWithRegistry(addr string, registry *core.Registry, opts ...grpc.ServerOption) *Node {
+	return &Node{
+		addr:     addr,
+		registry: registry,
+		pool:     NewGRPCPool(addr),
+		opts:     opts,
+	}
+}
+
+// Addr 返回节点的地址
+func (n *Node) Addr() string {
+	return n.addr
+}
+
+// Pool 返回节点的节点池，用于设置成员、加入集群等
+func (n *Node) Pool() *GRPCPool {
+	return n.pool
+}
+
+// Registry 返回保存本节点 Group 的 Registry
+func (n *Node) Registry() *core.Registry {
+	return n.registry
+}
+
+// SetLogger 设置节点、节点池和之后在本节点上创建的 Group 使用的 logger，应在 Serve 和创建 Group 之前调用。
+// Group 的 opts 中通过 core.WithLogger 指定的 logger 优先。
+func (n *Node) SetLogger(l *slog.Logger) {
+	n.mu.Lock()
+	n.logger = l
+	n.mu.Unlock()
+	n.pool.SetLogger(l)
+}
+
+func (n *Node) log() *slog.Logger {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.logger == nil {
+		return logging.Default()
+	}
+	return n.logger
+}
+
+// NewGroup 在本节点上创建 Group，并注册本节点的节点池，配置不合法时 panic
+func (n *Node) NewGroup(name string, cacheBytes int64, getter interfaces.Getter, algorithm string) *core.Group {
+	g, err := n.NewGroupWithOptions(name, cacheBytes, getter, core.WithPolicy(algorithm))
+	if err != nil {
+		panic(err)
+	}
+	return g
+}
+
+// NewGroupWithOptions 按 opts 在本节点上创建 Group 并注册本节点的节点池，配置不合法时返回错误
+func (n *Node) NewGroupWithOptions(name string, cacheBytes int64, getter interfaces.Getter, opts ...core.GroupOption) (*core.Group, error) {
+	opts = append([]core.GroupOption{core.WithLogger(n.log())}, opts...)
+	g, err := n.registry.NewGroupWithOptions(name, cacheBytes, getter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	g.RegisterPeers(n.pool)
+	return g, nil
+}
+
+// GetGroup 返回本节点上名为 name 的 Group，不存在时返回 nil
+func (n *Node) GetGroup(name string) *core.Group {
+	return n.registry.GetGroup(name)
+}
+
+// DeleteGroup 删除本节点上名为 name 的 Group，返回该 Group 是否存在
+func (n *Node) DeleteGroup(name string) bool {
+	return n.registry.DeleteGroup(name)
+}
+
+// ListGroups 返回本节点上所有 Group 的名字
+func (n *Node) ListGroups() []string {
+	return n.registry.ListGroups()
+}
+
+// Serve 在 lis 上提供 GroupCache 服务，直到节点停止。
+// 应在通过 Pool().Set 或 Pool().StartRaft 启动 Raft 之后调用，否则节点不处理 Raft 请求。
+func (n *Node) Serve(lis net.Listener) error {
+	n.mu.Lock()
+	if n.stopped {
+		n.mu.Unlock()
+		return grpc.ErrServerStopped
+	}
+	if n.server == nil {
+		n.server = NewGRPCServerWithResolver(n.pool.Raft(), n.registry.GetGroup, n.opts...)
+	}
+	s := n.server
+	n.mu.Unlock()
+	return s.Serve(lis)
+}
+
+// ListenAndServe 在节点地址上监听 TCP 连接并调用 Serve
+func (n *Node) ListenAndServe() error {
+	lis, err := net.Listen("tcp", n.addr)
+	if err != nil {
+		return err
+	}
+	n.log().Info("gRPC server listening", "addr", n.addr)
+	return n.Serve(lis)
+}
+
+// Stop 停止 gRPC 服务，并关闭节点池和 Raft
+func (n *Node) Stop() {
+	n.mu.Lock()
+	if n.stopped {
+		n.mu.Unlock()
+		return
+	}
+	n.stopped = true
+	s := n.server
+	n.mu.Unlock()
+	if s != nil {
+		s.Stop()
+	}
+	n.pool.Close()
+}
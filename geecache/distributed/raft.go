@@ -3,13 +3,17 @@ package distributed
 /*实现 Raft 算法，节点的选举、日志复制、心跳等*/
 import (
 	"GeeCache/geecache/geecachepb"
+	"GeeCache/geecache/logging"
 	"context"
+	"errors"
 	"fmt"
 	"hash/crc32"
+	"log/slog"
+	"math/rand"
+	"os"
+	"slices"
 	"sync"
 	"time"
-
-	"google.golang.org/grpc"
 )
 
 const (
@@ -18,150 +22,940 @@ const (
 	Leader
 )
 
+const (
+	defaultElectionTimeout   = time.Second            // 选举超时的下限，实际超时在 [t, 2t) 之间随机
+	defaultHeartbeatInterval = 200 * time.Millisecond // 领导者发送心跳的间隔
+	defaultSnapshotThreshold = 1000                   // 应用了这么多条日志后生成快照并压缩日志
+)
+
+var (
+	// ErrNotLeader 表示本节点不是领导者，调用方应通过 Leader() 找到领导者后重试
+	ErrNotLeader = errors.New("raft: not the leader")
+	// ErrLeadershipLost 表示提议的日志在提交前被新领导者覆盖
+	ErrLeadershipLost = errors.New("raft: leadership lost before the entry was committed")
+	// ErrStopped 表示 Raft 节点已经停止
+	ErrStopped = errors.New("raft: stopped")
+	// ErrMembershipChangePending 表示上一次成员变更尚未提交，或新领导者还没有提交本任期的日志
+	ErrMembershipChangePending = errors.New("raft: a membership change is already in progress")
+)
+
+// StateMachine 是 Raft 复制的状态机，已提交的日志按索引顺序依次传给 Apply。
+// 所有节点以相同顺序应用相同的命令，因此 Apply 必须是确定性的。
+type StateMachine interface {
+	Apply(command []byte) error
+}
+
+// Snapshotter 是支持快照的 StateMachine。实现了该接口的状态机会定期生成快照来压缩日志，
+// 落后太多的节点直接安装领导者的快照。
+type Snapshotter interface {
+	// Snapshot 序列化状态机的当前状态
+	Snapshot() ([]byte, error)
+	// Restore 用快照替换状态机的全部状态
+	Restore(data []byte) error
+}
+
+// RaftConfig 是创建 Raft 节点的配置，零值字段使用默认值
+type RaftConfig struct {
+	Self              string        // 本节点地址
+	Peers             []string      // 初始的投票节点，不包含 Self 时会自动加入；为空时等待领导者把本节点加入集群
+	StateMachine      StateMachine  // 已提交日志的应用对象，不能为空
+	Transport         RaftTransport // 节点间通信方式，默认使用 gRPC
+	ElectionTimeout   time.Duration // 选举超时的下限
+	HeartbeatInterval time.Duration // 心跳间隔，应远小于 ElectionTimeout
+	Storage           RaftStorage   // 持久化存储，默认使用不持久化的 MemoryStorage
+	SnapshotThreshold int64         // 生成快照的日志条数，小于 0 时不生成快照
+	// OnMembershipChange 在成员变更被应用到状态机之后调用，参数为变更后的全部投票节点
+	OnMembershipChange func(peers []string)
+	Logger             *slog.Logger // 为 nil 时使用 logging.Default()，每条日志都带有 raft 属性
+}
+
 type Raft struct {
-	mu          sync.Mutex
+	mu                sync.Mutex
+	self              string
+	id                int32 // 在 Raft 算法中使用整数类型，保证节点的唯一性
+	initialPeers      []string
+	peers             []string // 当前配置中的投票节点，成员变更条目写入日志后立即生效
+	configIndex       int64    // 当前配置所在的日志索引，0 表示来自快照或初始配置
+	onMembership      func(peers []string)
+	transport         RaftTransport
+	sm                StateMachine
+	storage           RaftStorage
+	electionTimeout   time.Duration
+	heartbeatInterval time.Duration
+	snapshotThreshold int64
+	logger            *slog.Logger
+
 	state       int32
 	currentTerm int32
-	votedFor    int32
-	log         []string
-	commitIndex int32
-	lastApplied int32
-	peers       []string
-	id          int32 // 在 Raft 算法中使用整数类型，保证节点的唯一性
-	grpcServer  *grpc.Server
-	grpcClients map[string]geecachepb.GroupCacheClient
+	votedFor    string
+	leader      string
+	// log[0] 是哨兵条目，保存日志起点之前最后一条日志的索引和任期，真实日志从 log[1] 开始
+	log         []*geecachepb.LogEntry
+	commitIndex int64
+	lastApplied int64
+	nextIndex   map[string]int64     // 领导者为每个节点记录的下一条待发送日志
+	matchIndex  map[string]int64     // 领导者为每个节点记录的已复制的最高日志
+	snapshot    *geecachepb.Snapshot // 最近的快照，发送给落后的节点
+	pending     *geecachepb.Snapshot // 从领导者收到、尚未应用到状态机的快照
+
+	deadline      time.Time // 跟随者和候选人在此之前未收到心跳则发起选举
+	lastContact   time.Time // 最近一次收到领导者请求的时间
+	lastHeartbeat time.Time
+	waiters       map[int64]*proposal
+	applyCond     *sync.Cond
+	stopCh        chan struct{}
+	stopped       bool
+	wg            sync.WaitGroup
+	// 每个跟随者一个常驻的复制协程，通道容量为 1，用于唤醒。同一跟随者同一时刻最多只有一个请求在途，
+	// 复制协程运行期间到来的唤醒合并为一次
+	replicators map[string]chan struct{}
+	ctx         context.Context // Stop 时取消，正在进行的 RPC 随之返回
+	cancel      context.CancelFunc
+}
+
+// proposal 记录一次 Propose，日志被应用后通过 done 返回结果
+type proposal struct {
+	term int32
+	done chan error
 }
 
+// NewRaft 创建一个使用 gRPC 通信、以 ClusterState 为状态机、不持久化的 Raft 节点
 func NewRaft(self string, peers []string) *Raft {
-	// 将 self 转换为唯一的 int id (可以使用哈希函数，确保唯一性)
-	id := int32(crc32.ChecksumIEEE([]byte(self))) // 通过 CRC32 哈希生成唯一 ID
+	r, err := NewRaftWithConfig(RaftConfig{
+		Self:         self,
+		Peers:        peers,
+		StateMachine: NewClusterState(),
+	})
+	if err != nil {
+		logging.Default().Error("failed to create raft node", "raft", self, "error", err)
+		os.Exit(1)
+	}
+	return r
+}
+
+// NewRaftWithConfig 按配置创建 Raft 节点，并从 cfg.Storage 恢复任期、投票、快照和日志。
+// 调用 Start 后才开始选举。
+func NewRaftWithConfig(cfg RaftConfig) (*Raft, error) {
+	if cfg.Transport == nil {
+		cfg.Transport = NewGRPCTransport()
+	}
+	if cfg.Storage == nil {
+		cfg.Storage = NewMemoryStorage()
+	}
+	if cfg.SnapshotThreshold == 0 {
+		cfg.SnapshotThreshold = defaultSnapshotThreshold
+	}
+	if cfg.ElectionTimeout <= 0 {
+		cfg.ElectionTimeout = defaultElectionTimeout
+	}
+	if cfg.HeartbeatInterval <= 0 {
+		cfg.HeartbeatInterval = defaultHeartbeatInterval
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = logging.Default()
+	}
+	peers := slices.Clone(cfg.Peers)
+	if len(peers) > 0 && !slices.Contains(peers, cfg.Self) {
+		peers = append(peers, cfg.Self)
+	}
 	r := &Raft{
-		id:          id,
-		peers:       peers,
-		state:       Follower,
-		currentTerm: 0,
-		votedFor:    -1,
-		grpcClients: make(map[string]geecachepb.GroupCacheClient),
+		self:              cfg.Self,
+		id:                int32(crc32.ChecksumIEEE([]byte(cfg.Self))), // 通过 CRC32 哈希生成唯一 ID
+		initialPeers:      peers,
+		peers:             peers,
+		onMembership:      cfg.OnMembershipChange,
+		transport:         cfg.Transport,
+		sm:                cfg.StateMachine,
+		storage:           cfg.Storage,
+		electionTimeout:   cfg.ElectionTimeout,
+		heartbeatInterval: cfg.HeartbeatInterval,
+		snapshotThreshold: cfg.SnapshotThreshold,
+		state:             Follower,
+		log:               []*geecachepb.LogEntry{{}},
+		waiters:           make(map[int64]*proposal),
+		stopCh:            make(chan struct{}),
+		replicators:       make(map[string]chan struct{}),
+		logger:            cfg.Logger.With("raft", cfg.Self),
 	}
+	r.ctx, r.cancel = context.WithCancel(context.Background())
+	r.applyCond = sync.NewCond(&r.mu)
+	if err := r.restore(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
 
-	for _, peer := range peers {
-		conn, err := grpc.Dial(peer, grpc.WithInsecure())
-		if err != nil {
-			fmt.Printf("Error connecting to peer %s: %v", peer, err)
-			return nil
+// restore 从存储中恢复状态，快照之后的日志在重新提交后再次应用
+func (r *Raft) restore() error {
+	state, snapshot, entries, err := r.storage.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load raft state: %w", err)
+	}
+	r.currentTerm, r.votedFor = state.Term, state.VotedFor
+	if snapshot != nil {
+		if ss, ok := r.sm.(Snapshotter); ok {
+			if err := ss.Restore(snapshot.GetData()); err != nil {
+				return fmt.Errorf("failed to restore raft snapshot: %w", err)
+			}
 		}
-		client := geecachepb.NewGroupCacheClient(conn)
-		r.grpcClients[peer] = client
+		r.log[0] = &geecachepb.LogEntry{Index: snapshot.GetLastIndex(), Term: snapshot.GetLastTerm()}
+		r.snapshot = snapshot
+		r.commitIndex, r.lastApplied = snapshot.GetLastIndex(), snapshot.GetLastIndex()
 	}
-	return r
+	r.log = append(r.log, entries...)
+	r.refreshConfigLocked()
+	return nil
+}
+
+// mustPersist 检查持久化的结果。状态没有落盘就继续运行会破坏 Raft 的安全性，因此直接退出。
+func (r *Raft) mustPersist(err error) {
+	if err != nil {
+		r.logger.Error("failed to persist state", "error", err)
+		os.Exit(1)
+	}
+}
+
+func (r *Raft) persistHardStateLocked() {
+	r.mustPersist(r.storage.SaveHardState(HardState{Term: r.currentTerm, VotedFor: r.votedFor}))
 }
 
-// 启动 Raft 节点并开始选举
+// 启动 Raft 节点的计时器和日志应用协程
 func (r *Raft) Start() {
-	go r.runElection()
+	r.mu.Lock()
+	r.resetElectionTimerLocked()
+	r.mu.Unlock()
+
+	r.wg.Add(2)
+	go r.run()
+	go r.applier()
 }
 
-// runElection 是选举过程的实现
-func (r *Raft) runElection() {
-	for {
-		time.Sleep(time.Second * 5)
+// Stop 停止 Raft 节点，等待中的 Propose 返回 ErrStopped
+func (r *Raft) Stop() {
+	r.mu.Lock()
+	if r.stopped {
+		r.mu.Unlock()
+		return
+	}
+	r.stopped = true
+	close(r.stopCh)
+	r.cancel()
+	for index, p := range r.waiters {
+		p.done <- ErrStopped
+		delete(r.waiters, index)
+	}
+	r.applyCond.Broadcast()
+	r.mu.Unlock()
+	r.wg.Wait()
+}
 
-		r.mu.Lock()
-		if r.state == Leader {
+// State 返回当前任期以及本节点是否为领导者
+func (r *Raft) State() (int32, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.currentTerm, r.state == Leader
+}
+
+// Leader 返回本节点所知的领导者地址，未知时返回空字符串
+func (r *Raft) Leader() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.leader
+}
+
+// CommitIndex 返回已提交的最高日志索引
+func (r *Raft) CommitIndex() int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.commitIndex
+}
+
+// Propose 由领导者把 command 写入日志，复制到多数节点并应用到本节点的状态机后返回其索引。
+// 本节点不是领导者时返回 ErrNotLeader；ctx 结束时返回 ctx.Err()，但日志仍可能在之后被提交。
+func (r *Raft) Propose(ctx context.Context, command []byte) (int64, error) {
+	return r.propose(ctx, command, nil)
+}
+
+// propose 写入一条日志并等待其被应用。reconfigure 不为 nil 时写入成员变更条目，
+// 新配置由 reconfigure 根据当前配置计算。
+func (r *Raft) propose(ctx context.Context, command []byte, reconfigure func(peers []string) []string) (int64, error) {
+	r.mu.Lock()
+	if r.stopped {
+		r.mu.Unlock()
+		return 0, ErrStopped
+	}
+	if r.state != Leader {
+		r.mu.Unlock()
+		return 0, ErrNotLeader
+	}
+	var peers []string
+	if reconfigure != nil {
+		// 单节点变更：新旧配置的多数派必然相交，但同一时间只能有一个未提交的变更；
+		// 新领导者还要先提交本任期的日志，确保之前任期遗留的变更已经生效
+		if r.configIndex > r.commitIndex || r.entryLocked(r.commitIndex).Term != r.currentTerm {
 			r.mu.Unlock()
-			continue
+			return 0, ErrMembershipChangePending
+		}
+		if peers = reconfigure(slices.Clone(r.peers)); len(peers) == 0 {
+			r.mu.Unlock()
+			return 0, errors.New("raft: cannot remove the last member")
 		}
+	}
+	index := r.appendLocked(command, peers)
+	p := &proposal{term: r.currentTerm, done: make(chan error, 1)}
+	r.waiters[index] = p
+	r.advanceCommitLocked() // 单节点集群可以直接提交
+	r.broadcastLocked()
+	r.mu.Unlock()
 
-		// 尝试发起选举
-		r.state = Candidate
-		r.currentTerm++
-		r.votedFor = r.id
+	select {
+	case err := <-p.done:
+		return index, err
+	case <-ctx.Done():
+		r.mu.Lock()
+		delete(r.waiters, index)
 		r.mu.Unlock()
+		return index, ctx.Err()
+	}
+}
 
-		votes := 1 // 当前节点投票给自己
-		for _, peer := range r.peers {
-			if peer == fmt.Sprintf("localhost:%d", r.id) {
-				continue
-			}
+// Members 返回当前配置中的投票节点
+func (r *Raft) Members() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.peers)
+}
+
+// AddMember 把 peer 以 weight 的权重加入集群，已是成员时只更新权重。
+// 本节点不是领导者时转发给领导者；变更提交并应用后返回新的成员列表。
+func (r *Raft) AddMember(ctx context.Context, peer string, weight int) ([]string, error) {
+	return r.changeMembership(ctx, &geecachepb.MembershipRequest{Peer: peer, Weight: int32(weight)})
+}
+
+// RemoveMember 把 peer 移出集群，移除领导者自己时它会在变更提交后退位
+func (r *Raft) RemoveMember(ctx context.Context, peer string) ([]string, error) {
+	return r.changeMembership(ctx, &geecachepb.MembershipRequest{Peer: peer, Remove: true})
+}
+
+// Join 请求 seed 所在的集群把本节点加入集群。本节点应以空的 Peers 创建，
+// 加入后由领导者复制日志或快照。
+func (r *Raft) Join(ctx context.Context, seed string, weight int) error {
+	_, err := r.transport.ChangeMembership(ctx, seed, &geecachepb.MembershipRequest{Peer: r.self, Weight: int32(weight)})
+	return err
+}
+
+// HandleChangeMembership 处理其他节点发来的成员变更请求
+func (r *Raft) HandleChangeMembership(ctx context.Context, req *geecachepb.MembershipRequest) (*geecachepb.MembershipResponse, error) {
+	peers, err := r.changeMembership(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return &geecachepb.MembershipResponse{Peers: peers}, nil
+}
+
+// changeMembership 由领导者把成员变更写入日志，非领导者转发给已知的领导者。
+// 变更同时作为 ClusterCommand 应用到状态机，用于记录节点权重。
+func (r *Raft) changeMembership(ctx context.Context, req *geecachepb.MembershipRequest) ([]string, error) {
+	if req.GetPeer() == "" {
+		return nil, errors.New("raft: empty peer in membership change")
+	}
+	r.mu.Lock()
+	isLeader, leader := r.state == Leader, r.leader
+	r.mu.Unlock()
+	if !isLeader {
+		if leader == "" || leader == r.self {
+			return nil, ErrNotLeader
+		}
+		resp, err := r.transport.ChangeMembership(ctx, leader, req)
+		if err != nil {
+			return nil, err
+		}
+		return resp.GetPeers(), nil
+	}
 
-			go r.sendVoteRequest(peer, &votes)
+	cmd := ClusterCommand{Op: OpAddPeer, Peer: req.GetPeer(), Weight: int(req.GetWeight())}
+	if req.GetRemove() {
+		cmd.Op = OpRemovePeer
+	}
+	var peers []string
+	_, err := r.propose(ctx, cmd.Encode(), func(current []string) []string {
+		if req.GetRemove() {
+			peers = slices.DeleteFunc(current, func(p string) bool { return p == req.GetPeer() })
+		} else if peers = current; !slices.Contains(current, req.GetPeer()) {
+			peers = append(current, req.GetPeer())
 		}
+		return peers
+	})
+	if err != nil {
+		return nil, err
+	}
+	return slices.Clone(peers), nil
+}
 
-		// 等待选举完成的判断
-		time.Sleep(time.Second * 2)
-		if votes > len(r.peers)/2 {
+// run 驱动选举超时和心跳
+func (r *Raft) run() {
+	defer r.wg.Done()
+	tick := min(r.heartbeatInterval, r.electionTimeout/10)
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stopCh:
+			return
+		case now := <-ticker.C:
 			r.mu.Lock()
-			r.state = Leader
+			switch {
+			case r.state == Leader && now.Sub(r.lastHeartbeat) >= r.heartbeatInterval:
+				r.broadcastLocked()
+			case r.state != Leader && now.After(r.deadline):
+				// 不在配置中的节点（尚未加入或已被移除）不发起选举
+				if slices.Contains(r.peers, r.self) {
+					r.startElectionLocked()
+				} else {
+					r.resetElectionTimerLocked()
+				}
+			}
 			r.mu.Unlock()
-			fmt.Printf("Node %d became leader\n", r.id)
-			r.sendHeartbeats()
-			break
 		}
 	}
 }
 
-// sendVoteRequest 发送投票请求
-func (r *Raft) sendVoteRequest(peer string, votes *int) {
-	conn, err := grpc.Dial(peer, grpc.WithInsecure())
+// resetElectionTimerLocked 在 [electionTimeout, 2*electionTimeout) 中随机选择下一次超时，
+// 避免多个节点同时发起选举而瓜分选票
+func (r *Raft) resetElectionTimerLocked() {
+	r.deadline = time.Now().Add(r.electionTimeout + time.Duration(rand.Int63n(int64(r.electionTimeout))))
+}
+
+func (r *Raft) startElectionLocked() {
+	r.state = Candidate
+	r.currentTerm++
+	r.votedFor = r.self
+	r.leader = ""
+	r.persistHardStateLocked()
+	r.resetElectionTimerLocked()
+	r.logger.Info("starting election", "term", r.currentTerm)
+
+	term := r.currentTerm
+	last := r.lastEntryLocked()
+	req := &geecachepb.RequestVoteRequest{
+		Term:         term,
+		CandidateId:  r.id,
+		Candidate:    r.self,
+		LastLogIndex: last.Index,
+		LastLogTerm:  last.Term,
+	}
+	votes := 1 // 当前节点投票给自己
+	if votes > len(r.peers)/2 {
+		r.becomeLeaderLocked()
+		return
+	}
+	for _, peer := range r.peers {
+		if peer == r.self {
+			continue
+		}
+		r.wg.Add(1)
+		go func(peer string) {
+			defer r.wg.Done()
+			ctx, cancel := context.WithTimeout(r.ctx, r.electionTimeout)
+			defer cancel()
+			resp, err := r.transport.RequestVote(ctx, peer, req)
+			if err != nil {
+				return
+			}
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			if resp.GetTerm() > r.currentTerm {
+				r.becomeFollowerLocked(resp.GetTerm())
+				return
+			}
+			if r.state != Candidate || r.currentTerm != term || !resp.GetVoteGranted() {
+				return
+			}
+			votes++
+			if votes > len(r.peers)/2 {
+				r.becomeLeaderLocked()
+			}
+		}(peer)
+	}
+}
+
+func (r *Raft) becomeFollowerLocked(term int32) {
+	if term > r.currentTerm {
+		r.currentTerm = term
+		r.votedFor = ""
+		r.persistHardStateLocked()
+	}
+	r.state = Follower
+}
+
+func (r *Raft) becomeLeaderLocked() {
+	r.state = Leader
+	r.leader = r.self
+	r.logger.Info("became leader", "term", r.currentTerm)
+
+	last := r.lastEntryLocked().Index
+	r.nextIndex = make(map[string]int64, len(r.peers))
+	r.matchIndex = make(map[string]int64, len(r.peers))
+	for _, peer := range r.peers {
+		r.nextIndex[peer] = last + 1
+	}
+	// 写入一条空日志，提交它的同时也提交了之前任期遗留的日志
+	r.appendLocked(nil, nil)
+	r.advanceCommitLocked()
+	r.broadcastLocked()
+}
+
+// appendLocked 在领导者的日志末尾追加一条当前任期的日志，返回其索引。
+// peers 不为空时该条目是成员变更，新配置立即生效。
+func (r *Raft) appendLocked(command []byte, peers []string) int64 {
+	index := r.lastEntryLocked().Index + 1
+	entry := &geecachepb.LogEntry{Index: index, Term: r.currentTerm, Command: command, Peers: peers}
+	r.mustPersist(r.storage.Append([]*geecachepb.LogEntry{entry}))
+	r.log = append(r.log, entry)
+	r.matchIndex[r.self] = index
+	if len(peers) > 0 {
+		r.refreshConfigLocked()
+	}
+	return index
+}
+
+// configAtLocked 返回 index 处生效的配置及其所在的日志索引：index 之前最新的成员变更条目，
+// 没有时使用快照中的配置或初始配置
+func (r *Raft) configAtLocked(index int64) ([]string, int64) {
+	for i := index; i > r.log[0].Index; i-- {
+		if entry := r.entryLocked(i); len(entry.Peers) > 0 {
+			return entry.Peers, i
+		}
+	}
+	if len(r.snapshot.GetPeers()) > 0 {
+		return r.snapshot.GetPeers(), 0
+	}
+	return r.initialPeers, 0
+}
+
+// refreshConfigLocked 在日志变化后重新计算当前配置。成员变更条目一旦写入日志就生效，
+// 被截断时配置也随之回退。
+func (r *Raft) refreshConfigLocked() {
+	r.peers, r.configIndex = r.configAtLocked(r.lastEntryLocked().Index)
+	if r.state != Leader {
+		return
+	}
+	for _, peer := range r.peers {
+		if _, ok := r.nextIndex[peer]; !ok {
+			r.nextIndex[peer] = r.lastEntryLocked().Index + 1
+		}
+	}
+}
+
+func (r *Raft) lastEntryLocked() *geecachepb.LogEntry {
+	return r.log[len(r.log)-1]
+}
+
+// entryLocked 返回索引为 index 的日志，index 必须在 [log[0].Index, lastIndex] 之间
+func (r *Raft) entryLocked(index int64) *geecachepb.LogEntry {
+	return r.log[index-r.log[0].Index]
+}
+
+// broadcastLocked 唤醒所有跟随者的复制协程，发送日志或心跳
+func (r *Raft) broadcastLocked() {
+	r.lastHeartbeat = time.Now()
+	if r.stopped {
+		return
+	}
+	for _, peer := range r.peers {
+		if peer == r.self {
+			continue
+		}
+		wake, ok := r.replicators[peer]
+		if !ok {
+			wake = make(chan struct{}, 1)
+			r.replicators[peer] = wake
+			r.wg.Add(1)
+			go r.replicator(peer, wake)
+		}
+		select {
+		case wake <- struct{}{}:
+		default: // 已经有一次未处理的唤醒
+		}
+	}
+}
+
+// replicator 是 peer 的复制协程，每次被唤醒时调用一次 replicate。
+// peer 不在当前配置中时退出，之后重新加入时由 broadcastLocked 再次创建。
+func (r *Raft) replicator(peer string, wake chan struct{}) {
+	defer r.wg.Done()
+	for {
+		select {
+		case <-r.stopCh:
+			return
+		case <-wake:
+		}
+		r.mu.Lock()
+		if !slices.Contains(r.peers, peer) {
+			delete(r.replicators, peer)
+			r.mu.Unlock()
+			return
+		}
+		r.mu.Unlock()
+		r.replicate(peer)
+	}
+}
+
+// replicate 向 peer 发送一次 AppendEntries，并根据结果调整 nextIndex/matchIndex。
+// peer 需要的日志已经被压缩时改为发送快照。
+func (r *Raft) replicate(peer string) {
+	r.mu.Lock()
+	if r.state != Leader {
+		r.mu.Unlock()
+		return
+	}
+	if r.nextIndex[peer] <= r.log[0].Index {
+		r.mu.Unlock()
+		r.sendSnapshot(peer)
+		return
+	}
+	term := r.currentTerm
+	prev := r.entryLocked(r.nextIndex[peer] - 1)
+	entries := slices.Clone(r.log[prev.Index-r.log[0].Index+1:])
+	req := &geecachepb.AppendEntriesRequest{
+		Term:         term,
+		LeaderId:     r.id,
+		Leader:       r.self,
+		PrevLogIndex: prev.Index,
+		PrevLogTerm:  prev.Term,
+		Entries:      entries,
+		LeaderCommit: r.commitIndex,
+	}
+	r.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(r.ctx, r.electionTimeout)
+	defer cancel()
+	resp, err := r.transport.AppendEntries(ctx, peer, req)
 	if err != nil {
-		fmt.Println("Failed to connect to peer:", peer)
 		return
 	}
-	defer conn.Close()
 
-	client := geecachepb.NewGroupCacheClient(conn)
-	req := &geecachepb.RequestVoteRequest{
-		Term:        r.currentTerm,
-		CandidateId: r.id,
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if resp.GetTerm() > r.currentTerm {
+		r.becomeFollowerLocked(resp.GetTerm())
+		return
+	}
+	if r.state != Leader || r.currentTerm != term {
+		return
+	}
+	if resp.GetSuccess() {
+		// 多个请求可能并发返回，只向前推进
+		if match := prev.Index + int64(len(entries)); match > r.matchIndex[peer] {
+			r.matchIndex[peer] = match
+			r.nextIndex[peer] = match + 1
+			r.advanceCommitLocked()
+		}
+		return
 	}
+	next := resp.GetConflictIndex()
+	if next <= r.matchIndex[peer] {
+		next = r.matchIndex[peer] + 1
+	}
+	r.nextIndex[peer] = max(min(next, r.lastEntryLocked().Index+1), 1)
+}
+
+// sendSnapshot 向 peer 发送最近的快照
+func (r *Raft) sendSnapshot(peer string) {
+	r.mu.Lock()
+	if r.state != Leader || r.snapshot == nil {
+		r.mu.Unlock()
+		return
+	}
+	term := r.currentTerm
+	snapshot := r.snapshot
+	req := &geecachepb.InstallSnapshotRequest{
+		Term:     term,
+		LeaderId: r.id,
+		Leader:   r.self,
+		Snapshot: snapshot,
+	}
+	r.mu.Unlock()
 
-	res, err := client.RequestVote(context.Background(), req)
+	ctx, cancel := context.WithTimeout(r.ctx, r.electionTimeout)
+	defer cancel()
+	resp, err := r.transport.InstallSnapshot(ctx, peer, req)
 	if err != nil {
-		fmt.Println("Failed to send vote request:", err)
 		return
 	}
 
-	if res.VoteGranted {
-		fmt.Printf("Vote granted by %s\n", peer)
-		(*votes)++
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if resp.GetTerm() > r.currentTerm {
+		r.becomeFollowerLocked(resp.GetTerm())
+		return
+	}
+	if r.state != Leader || r.currentTerm != term {
+		return
+	}
+	if match := snapshot.GetLastIndex(); match > r.matchIndex[peer] {
+		r.matchIndex[peer] = match
+		r.nextIndex[peer] = match + 1
+		r.advanceCommitLocked()
+	}
+}
+
+// compactLocked 用状态机在 index 处的快照替换 index 及之前的日志
+func (r *Raft) compactLocked(index int64, term int32, data []byte) {
+	peers, _ := r.configAtLocked(index)
+	snapshot := &geecachepb.Snapshot{LastIndex: index, LastTerm: term, Data: data, Peers: peers}
+	r.log = append([]*geecachepb.LogEntry{{Index: index, Term: term}}, r.log[index-r.log[0].Index+1:]...)
+	r.snapshot = snapshot
+	r.mustPersist(r.storage.SaveSnapshot(snapshot, r.log[1:]))
+}
+
+// advanceCommitLocked 把 commitIndex 推进到已复制到多数节点的当前任期日志。
+// 之前任期的日志不能仅凭副本数提交，只能随当前任期的日志一起提交。
+func (r *Raft) advanceCommitLocked() {
+	for n := r.lastEntryLocked().Index; n > r.commitIndex; n-- {
+		if r.entryLocked(n).Term != r.currentTerm {
+			break
+		}
+		count := 0
+		for _, peer := range r.peers {
+			if r.matchIndex[peer] >= n {
+				count++
+			}
+		}
+		if count > len(r.peers)/2 {
+			r.commitIndex = n
+			r.applyCond.Broadcast()
+			// 把自己移出集群的领导者在变更提交后退位
+			if !slices.Contains(r.peers, r.self) && r.commitIndex >= r.configIndex {
+				r.logger.Info("removed from the cluster, stepping down")
+				r.state = Follower
+				r.leader = ""
+			}
+			return
+		}
 	}
 }
 
-// sendHeartbeats 领导者定期发送心跳
-func (r *Raft) sendHeartbeats() {
+// applier 按顺序把已提交的日志和收到的快照应用到状态机，应用时不持有锁。
+// 应用的日志足够多时生成快照并压缩日志。
+func (r *Raft) applier() {
+	defer r.wg.Done()
+	ss, canSnapshot := r.sm.(Snapshotter)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if peers, _ := r.configAtLocked(r.lastApplied); len(peers) > 0 {
+		r.notifyMembershipLocked(peers)
+	}
 	for {
-		if r.state != Leader {
+		for !r.stopped && r.pending == nil && r.lastApplied >= r.commitIndex {
+			r.applyCond.Wait()
+		}
+		if r.stopped {
 			return
 		}
+		if snapshot := r.pending; snapshot != nil {
+			r.pending = nil
+			r.mu.Unlock()
+			var err error
+			if canSnapshot {
+				err = ss.Restore(snapshot.GetData())
+			} else {
+				err = fmt.Errorf("state machine %T does not support snapshots", r.sm)
+			}
+			if err != nil {
+				r.logger.Error("failed to install snapshot", "index", snapshot.GetLastIndex(), "error", err)
+			}
+			if r.onMembership != nil && len(snapshot.GetPeers()) > 0 {
+				r.onMembership(slices.Clone(snapshot.GetPeers()))
+			}
+			r.mu.Lock()
+			r.lastApplied = max(r.lastApplied, snapshot.GetLastIndex())
+			// 快照覆盖的提议无法确认结果，按领导权丢失处理
+			for index, p := range r.waiters {
+				if index <= snapshot.GetLastIndex() {
+					delete(r.waiters, index)
+					p.done <- ErrLeadershipLost
+				}
+			}
+			continue
+		}
 
-		for _, peer := range r.peers {
-			if peer == fmt.Sprintf("localhost:%d", r.id) {
-				continue
+		base := r.log[0].Index
+		entries := slices.Clone(r.log[r.lastApplied-base+1 : r.commitIndex-base+1])
+		r.mu.Unlock()
+
+		results := make([]error, len(entries))
+		for i, entry := range entries {
+			if entry.Command != nil {
+				results[i] = r.sm.Apply(entry.Command)
+			}
+			if r.onMembership != nil && len(entry.Peers) > 0 {
+				r.onMembership(slices.Clone(entry.Peers))
+			}
+		}
+		// 此时状态机恰好处于最后一条日志之后的状态，可以生成快照
+		last := entries[len(entries)-1]
+		var data []byte
+		takeSnapshot := canSnapshot && r.snapshotThreshold > 0 && last.Index-base >= r.snapshotThreshold
+		if takeSnapshot {
+			var err error
+			if data, err = ss.Snapshot(); err != nil {
+				r.logger.Error("failed to take snapshot", "index", last.Index, "error", err)
+				takeSnapshot = false
 			}
-			go r.sendHeartbeat(peer)
 		}
 
-		time.Sleep(time.Second * 1)
+		r.mu.Lock()
+		if takeSnapshot && last.Index > r.log[0].Index {
+			r.compactLocked(last.Index, last.Term, data)
+		}
+		for i, entry := range entries {
+			r.lastApplied = entry.Index
+			p, ok := r.waiters[entry.Index]
+			if !ok {
+				continue
+			}
+			delete(r.waiters, entry.Index)
+			if p.term != entry.Term {
+				p.done <- ErrLeadershipLost
+			} else {
+				p.done <- results[i]
+			}
+		}
 	}
 }
 
-// sendHeartbeat 发送心跳
-func (r *Raft) sendHeartbeat(peer string) {
-	conn, err := grpc.Dial(peer, grpc.WithInsecure())
-	if err != nil {
-		fmt.Println("Failed to connect to peer:", peer)
+// notifyMembershipLocked 在不持有锁的情况下调用 OnMembershipChange
+func (r *Raft) notifyMembershipLocked(peers []string) {
+	if r.onMembership == nil {
 		return
 	}
-	defer conn.Close()
+	peers = slices.Clone(peers)
+	r.mu.Unlock()
+	r.onMembership(peers)
+	r.mu.Lock()
+}
 
-	client := geecachepb.NewGroupCacheClient(conn)
-	req := &geecachepb.AppendEntriesRequest{
-		Term:     r.currentTerm,
-		LeaderId: r.id,
+// HandleRequestVote 处理候选人的投票请求
+func (r *Raft) HandleRequestVote(req *geecachepb.RequestVoteRequest) *geecachepb.RequestVoteResponse {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// 领导者仍然有效时忽略投票请求，避免已被移出集群、收不到心跳的节点用更大的任期打断集群
+	if r.state == Leader || (r.leader != "" && time.Since(r.lastContact) < r.electionTimeout) {
+		return &geecachepb.RequestVoteResponse{Term: r.currentTerm}
+	}
+	if req.GetTerm() > r.currentTerm {
+		r.becomeFollowerLocked(req.GetTerm())
+	}
+	resp := &geecachepb.RequestVoteResponse{Term: r.currentTerm}
+	if req.GetTerm() < r.currentTerm || (r.votedFor != "" && r.votedFor != req.GetCandidate()) {
+		return resp
+	}
+	// 只投票给日志至少和自己一样新的候选人，保证新领导者包含所有已提交的日志
+	last := r.lastEntryLocked()
+	if req.GetLastLogTerm() < last.Term || (req.GetLastLogTerm() == last.Term && req.GetLastLogIndex() < last.Index) {
+		return resp
 	}
+	r.votedFor = req.GetCandidate()
+	r.persistHardStateLocked()
+	r.resetElectionTimerLocked()
+	resp.VoteGranted = true
+	return resp
+}
 
-	_, err = client.AppendEntries(context.Background(), req)
-	if err != nil {
-		fmt.Println("Failed to send heartbeat:", err)
+// HandleAppendEntries 处理领导者的日志复制和心跳请求
+func (r *Raft) HandleAppendEntries(req *geecachepb.AppendEntriesRequest) *geecachepb.AppendEntriesResponse {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if req.GetTerm() < r.currentTerm {
+		return &geecachepb.AppendEntriesResponse{Term: r.currentTerm}
+	}
+	r.becomeFollowerLocked(req.GetTerm())
+	r.leader = req.GetLeader()
+	r.lastContact = time.Now()
+	r.resetElectionTimerLocked()
+	resp := &geecachepb.AppendEntriesResponse{Term: r.currentTerm}
+
+	// 日志匹配检查：prev_log_index 处的日志任期必须一致
+	last := r.lastEntryLocked()
+	prevIndex := req.GetPrevLogIndex()
+	if prevIndex > last.Index {
+		resp.ConflictIndex = last.Index + 1
+		return resp
+	}
+	if prevIndex >= r.log[0].Index {
+		if term := r.entryLocked(prevIndex).Term; term != req.GetPrevLogTerm() {
+			// 跳过整个冲突任期，避免逐条回退
+			conflict := prevIndex
+			for conflict > r.log[0].Index+1 && r.entryLocked(conflict-1).Term == term {
+				conflict--
+			}
+			resp.ConflictIndex = conflict
+			return resp
+		}
+	}
+
+	var appended []*geecachepb.LogEntry
+	for _, entry := range req.GetEntries() {
+		if entry.Index <= r.log[0].Index {
+			continue
+		}
+		if entry.Index <= r.lastEntryLocked().Index {
+			if r.entryLocked(entry.Index).Term == entry.Term {
+				continue
+			}
+			// 与领导者冲突的日志及其之后的日志全部删除
+			r.log = r.log[:entry.Index-r.log[0].Index]
+		}
+		r.log = append(r.log, entry)
+		appended = append(appended, entry)
+	}
+	// 新日志落盘后才能回复领导者
+	if len(appended) > 0 {
+		r.mustPersist(r.storage.Append(appended))
+		r.refreshConfigLocked()
+	}
+
+	// 只能提交与领导者确认一致的日志，即 prev_log_index 加上本次追加的条目
+	lastNew := prevIndex + int64(len(req.GetEntries()))
+	if commit := min(req.GetLeaderCommit(), lastNew); commit > r.commitIndex {
+		r.commitIndex = commit
+		r.applyCond.Broadcast()
+	}
+	resp.Success = true
+	return resp
+}
+
+// HandleInstallSnapshot 安装领导者发来的快照，替换快照覆盖的日志
+func (r *Raft) HandleInstallSnapshot(req *geecachepb.InstallSnapshotRequest) *geecachepb.InstallSnapshotResponse {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if req.GetTerm() < r.currentTerm {
+		return &geecachepb.InstallSnapshotResponse{Term: r.currentTerm}
+	}
+	r.becomeFollowerLocked(req.GetTerm())
+	r.leader = req.GetLeader()
+	r.lastContact = time.Now()
+	r.resetElectionTimerLocked()
+	resp := &geecachepb.InstallSnapshotResponse{Term: r.currentTerm}
+
+	snapshot := req.GetSnapshot()
+	if snapshot == nil || snapshot.GetLastIndex() <= r.commitIndex {
+		return resp
+	}
+	index, term := snapshot.GetLastIndex(), snapshot.GetLastTerm()
+	if index < r.lastEntryLocked().Index && r.entryLocked(index).Term == term {
+		// 保留快照之后与领导者一致的日志
+		r.log = append([]*geecachepb.LogEntry{{Index: index, Term: term}}, r.log[index-r.log[0].Index+1:]...)
+	} else {
+		r.log = []*geecachepb.LogEntry{{Index: index, Term: term}}
 	}
+	r.logger.Info("installing snapshot", "index", index, "leader", req.GetLeader())
+	r.snapshot = snapshot
+	r.pending = snapshot
+	r.commitIndex = index
+	r.mustPersist(r.storage.SaveSnapshot(snapshot, r.log[1:]))
+	r.refreshConfigLocked()
+	r.applyCond.Broadcast()
+	return resp
 }
@@ -2,12 +2,17 @@ package distributed_test
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net"
+	"slices"
+	"sync"
 	"testing"
 	"time"
 
+	"GeeCache/geecache/distributed"
 	"GeeCache/geecache/geecachepb"
 
 	"google.golang.org/grpc"
@@ -32,13 +37,14 @@ func (s *mockRaftServer) AppendEntries(ctx context.Context, req *geecachepb.Appe
 }
 
 func startGRPCServer(t *testing.T) string {
-	lis, err := net.Listen("tcp", ":50051")
+	lis, err := net.Listen("tcp", "127.0.0.1:0") // 随机端口，-count 大于 1 时不会冲突
 	if err != nil {
 		t.Fatalf("failed to listen: %v", err)
 	}
 
 	grpcServer := grpc.NewServer()
 	geecachepb.RegisterGroupCacheServer(grpcServer, &mockRaftServer{})
+	t.Cleanup(grpcServer.Stop)
 
 	// 创建一个通道用于传递错误
 	errChan := make(chan error, 1)
@@ -63,7 +69,7 @@ func startGRPCServer(t *testing.T) string {
 	if err != nil {
 		t.Fatalf("gRPC server failed: %v", err) // 确保 t.Fatalf 在主 Goroutine 中调用
 	}
-	return ":50051"
+	return lis.Addr().String()
 }
 
 // 模拟 Raft 节点
@@ -135,3 +141,497 @@ func TestRaft(t *testing.T) {
 	// 模拟发送心跳
 	raftNode.sendHeartbeat(serverAddr)
 }
+
+// memNetwork 在进程内直接调用其他节点的 Raft，可以隔离节点来模拟网络分区
+type memNetwork struct {
+	mu       sync.Mutex
+	nodes    map[string]*distributed.Raft
+	isolated map[string]bool
+}
+
+// memTransport 是 memNetwork 上某个节点的发送端
+type memTransport struct {
+	net  *memNetwork
+	self string
+}
+
+func (n *memNetwork) target(from, to string) (*distributed.Raft, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.isolated[from] || n.isolated[to] {
+		return nil, errors.New("network partitioned")
+	}
+	return n.nodes[to], nil
+}
+
+func (n *memNetwork) isolate(node string, isolated bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.isolated[node] = isolated
+}
+
+func (t *memTransport) RequestVote(ctx context.Context, peer string, req *geecachepb.RequestVoteRequest) (*geecachepb.RequestVoteResponse, error) {
+	r, err := t.net.target(t.self, peer)
+	if err != nil {
+		return nil, err
+	}
+	return r.HandleRequestVote(req), nil
+}
+
+func (t *memTransport) AppendEntries(ctx context.Context, peer string, req *geecachepb.AppendEntriesRequest) (*geecachepb.AppendEntriesResponse, error) {
+	r, err := t.net.target(t.self, peer)
+	if err != nil {
+		return nil, err
+	}
+	return r.HandleAppendEntries(req), nil
+}
+
+func (t *memTransport) InstallSnapshot(ctx context.Context, peer string, req *geecachepb.InstallSnapshotRequest) (*geecachepb.InstallSnapshotResponse, error) {
+	r, err := t.net.target(t.self, peer)
+	if err != nil {
+		return nil, err
+	}
+	return r.HandleInstallSnapshot(req), nil
+}
+
+func (t *memTransport) ChangeMembership(ctx context.Context, peer string, req *geecachepb.MembershipRequest) (*geecachepb.MembershipResponse, error) {
+	r, err := t.net.target(t.self, peer)
+	if err != nil {
+		return nil, err
+	}
+	return r.HandleChangeMembership(ctx, req)
+}
+
+// recorder 是记录所有已应用命令的状态机，同时记录最近一次应用的成员列表
+type recorder struct {
+	mu       sync.Mutex
+	commands []string
+	members  []string
+}
+
+func (s *recorder) setMembers(peers []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	slices.Sort(peers)
+	s.members = peers
+}
+
+func (s *recorder) appliedMembers() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.members
+}
+
+func (s *recorder) Apply(command []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.commands = append(s.commands, string(command))
+	return nil
+}
+
+func (s *recorder) Snapshot() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return json.Marshal(s.commands)
+}
+
+func (s *recorder) Restore(data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.commands = nil
+	return json.Unmarshal(data, &s.commands)
+}
+
+func (s *recorder) applied() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.commands)
+}
+
+type raftCluster struct {
+	net               *memNetwork
+	peers             []string
+	snapshotThreshold int64
+	nodes             map[string]*distributed.Raft
+	states            map[string]*recorder
+	storages          map[string]*distributed.MemoryStorage
+}
+
+func newRaftCluster(t *testing.T, n int, snapshotThreshold int64) *raftCluster {
+	c := &raftCluster{
+		net:               &memNetwork{nodes: make(map[string]*distributed.Raft), isolated: make(map[string]bool)},
+		snapshotThreshold: snapshotThreshold,
+		nodes:             make(map[string]*distributed.Raft),
+		states:            make(map[string]*recorder),
+		storages:          make(map[string]*distributed.MemoryStorage),
+	}
+	for i := 0; i < n; i++ {
+		peer := fmt.Sprintf("node%d", i)
+		c.peers = append(c.peers, peer)
+		c.storages[peer] = distributed.NewMemoryStorage()
+	}
+	for _, peer := range c.peers {
+		c.start(t, peer, c.peers)
+	}
+	t.Cleanup(func() {
+		for _, r := range c.nodes {
+			r.Stop()
+		}
+	})
+	return c
+}
+
+// start 用 peer 已有的存储创建并启动节点，模拟进程重启；bootstrap 为空时节点等待加入集群
+func (c *raftCluster) start(t *testing.T, peer string, bootstrap []string) {
+	t.Helper()
+	if _, ok := c.storages[peer]; !ok {
+		c.storages[peer] = distributed.NewMemoryStorage()
+	}
+	state := &recorder{}
+	r, err := distributed.NewRaftWithConfig(distributed.RaftConfig{
+		Self:               peer,
+		Peers:              bootstrap,
+		StateMachine:       state,
+		OnMembershipChange: state.setMembers,
+		Transport:          &memTransport{net: c.net, self: peer},
+		ElectionTimeout:    50 * time.Millisecond,
+		HeartbeatInterval:  10 * time.Millisecond,
+		Storage:            c.storages[peer],
+		SnapshotThreshold:  c.snapshotThreshold,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.net.mu.Lock()
+	c.net.nodes[peer] = r
+	c.net.mu.Unlock()
+	c.nodes[peer], c.states[peer] = r, state
+	r.Start()
+}
+
+// leader 等待并返回 except 之外唯一的领导者
+func (c *raftCluster) leader(t *testing.T, except ...string) string {
+	t.Helper()
+	var leader string
+	waitFor(t, 2*time.Second, func() bool {
+		leader = ""
+		for peer, r := range c.nodes {
+			if _, isLeader := r.State(); isLeader && !slices.Contains(except, peer) {
+				if leader != "" {
+					return false
+				}
+				leader = peer
+			}
+		}
+		return leader != ""
+	})
+	return leader
+}
+
+func waitFor(t *testing.T, timeout time.Duration, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("condition not met within %v", timeout)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestRaftReplicatesCommands(t *testing.T) {
+	c := newRaftCluster(t, 3, -1)
+	leader := c.leader(t)
+
+	var want []string
+	for i := 0; i < 5; i++ {
+		cmd := fmt.Sprintf("cmd%d", i)
+		want = append(want, cmd)
+		if _, err := c.nodes[leader].Propose(context.Background(), []byte(cmd)); err != nil {
+			t.Fatalf("propose %s: %v", cmd, err)
+		}
+	}
+	// 领导者返回时已经应用，跟随者在下一次心跳后应用
+	if got := c.states[leader].applied(); !slices.Equal(got, want) {
+		t.Fatalf("leader applied %v, want %v", got, want)
+	}
+	for peer, s := range c.states {
+		waitFor(t, time.Second, func() bool { return slices.Equal(s.applied(), want) })
+		if c.nodes[peer].Leader() != leader {
+			t.Errorf("%s thinks the leader is %q, want %q", peer, c.nodes[peer].Leader(), leader)
+		}
+	}
+
+	for _, peer := range c.peers {
+		if peer != leader {
+			if _, err := c.nodes[peer].Propose(context.Background(), []byte("x")); !errors.Is(err, distributed.ErrNotLeader) {
+				t.Fatalf("propose on follower: expected ErrNotLeader, got %v", err)
+			}
+			break
+		}
+	}
+}
+
+// slowTransport 让每个 AppendEntries 耗时 delay，并记录发往每个节点的在途请求数
+type slowTransport struct {
+	*memTransport
+	delay time.Duration
+
+	mu       sync.Mutex
+	inflight map[string]int
+	peak     int // 发往同一节点的在途请求数的最大值
+}
+
+func (t *slowTransport) AppendEntries(ctx context.Context, peer string, req *geecachepb.AppendEntriesRequest) (*geecachepb.AppendEntriesResponse, error) {
+	t.mu.Lock()
+	t.inflight[peer]++
+	t.peak = max(t.peak, t.inflight[peer])
+	t.mu.Unlock()
+	defer func() {
+		t.mu.Lock()
+		t.inflight[peer]--
+		t.mu.Unlock()
+	}()
+	select {
+	case <-time.After(t.delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return t.memTransport.AppendEntries(ctx, peer, req)
+}
+
+func (t *slowTransport) stats() (inflight, peak int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, n := range t.inflight {
+		inflight += n
+	}
+	return inflight, t.peak
+}
+
+// 心跳间隔小于一次 AppendEntries 的耗时时，发往同一跟随者的请求不会堆积；Stop 返回时没有在途的请求
+func TestRaftOneReplicationPerPeer(t *testing.T) {
+	net := &memNetwork{nodes: make(map[string]*distributed.Raft), isolated: make(map[string]bool)}
+	peers := []string{"node0", "node1", "node2"}
+	transports := make(map[string]*slowTransport)
+	for _, peer := range peers {
+		transports[peer] = &slowTransport{memTransport: &memTransport{net: net, self: peer}, delay: 30 * time.Millisecond, inflight: make(map[string]int)}
+		r, err := distributed.NewRaftWithConfig(distributed.RaftConfig{
+			Self:              peer,
+			Peers:             peers,
+			StateMachine:      &recorder{},
+			Transport:         transports[peer],
+			ElectionTimeout:   100 * time.Millisecond,
+			HeartbeatInterval: 5 * time.Millisecond,
+			SnapshotThreshold: -1,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		net.nodes[peer] = r
+	}
+	for _, r := range net.nodes {
+		r.Start()
+	}
+
+	var leader *distributed.Raft
+	waitFor(t, 2*time.Second, func() bool {
+		for _, r := range net.nodes {
+			if _, isLeader := r.State(); isLeader {
+				leader = r
+				return true
+			}
+		}
+		return false
+	})
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, err := leader.Propose(context.Background(), []byte(fmt.Sprintf("cmd%d", i))); err != nil {
+				t.Errorf("propose: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	time.Sleep(100 * time.Millisecond)
+
+	for _, r := range net.nodes {
+		r.Stop()
+	}
+	for peer, tr := range transports {
+		inflight, peak := tr.stats()
+		if peak > 1 {
+			t.Errorf("%s had %d AppendEntries in flight to one peer", peer, peak)
+		}
+		if inflight != 0 {
+			t.Errorf("%s still had %d AppendEntries in flight after Stop", peer, inflight)
+		}
+	}
+}
+
+func TestRaftLeaderFailover(t *testing.T) {
+	c := newRaftCluster(t, 3, -1)
+	old := c.leader(t)
+	if _, err := c.nodes[old].Propose(context.Background(), []byte("committed")); err != nil {
+		t.Fatal(err)
+	}
+
+	// 隔离旧领导者，它写入的日志无法提交
+	c.net.isolate(old, true)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := c.nodes[old].Propose(ctx, []byte("lost")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("isolated leader committed a command: %v", err)
+	}
+
+	// 剩下的多数节点选出新领导者并继续提交
+	leader := c.leader(t, old)
+	if _, err := c.nodes[leader].Propose(context.Background(), []byte("after")); err != nil {
+		t.Fatal(err)
+	}
+
+	// 恢复网络后旧领导者退位，未提交的日志被新领导者的日志覆盖
+	c.net.isolate(old, false)
+	want := []string{"committed", "after"}
+	for _, s := range c.states {
+		waitFor(t, 2*time.Second, func() bool { return slices.Equal(s.applied(), want) })
+	}
+	if _, isLeader := c.nodes[old].State(); isLeader {
+		t.Fatalf("old leader %s did not step down", old)
+	}
+}
+
+func TestClusterState(t *testing.T) {
+	s := distributed.NewClusterState()
+	commands := []distributed.ClusterCommand{
+		{Op: distributed.OpAddPeer, Peer: "a:8001", Weight: 2},
+		{Op: distributed.OpAddPeer, Peer: "b:8001"},
+		{Op: distributed.OpRemovePeer, Peer: "a:8001"},
+		{Op: distributed.OpSetGroup, Group: "scores", Config: &distributed.GroupConfig{CacheBytes: 1 << 20, Algorithm: "lru"}},
+	}
+	for _, cmd := range commands {
+		if err := s.Apply(cmd.Encode()); err != nil {
+			t.Fatalf("apply %+v: %v", cmd, err)
+		}
+	}
+	if peers := s.Peers(); len(peers) != 1 || peers["b:8001"] != 1 {
+		t.Fatalf("unexpected peers %v", peers)
+	}
+	if cfg := s.Groups()["scores"]; cfg.CacheBytes != 1<<20 || cfg.Algorithm != "lru" {
+		t.Fatalf("unexpected group config %+v", cfg)
+	}
+	if err := s.Apply(distributed.ClusterCommand{Op: "bogus"}.Encode()); err == nil {
+		t.Fatal("expected an error for an unknown command")
+	}
+}
+
+func TestRaftRestartKeepsLog(t *testing.T) {
+	c := newRaftCluster(t, 3, -1)
+	leader := c.leader(t)
+	if _, err := c.nodes[leader].Propose(context.Background(), []byte("a")); err != nil {
+		t.Fatal(err)
+	}
+
+	// 所有节点同时重启，状态只能从存储中恢复
+	for _, peer := range c.peers {
+		waitFor(t, time.Second, func() bool { return len(c.states[peer].applied()) == 1 })
+		c.nodes[peer].Stop()
+	}
+	for _, peer := range c.peers {
+		c.start(t, peer, c.peers)
+	}
+	leader = c.leader(t)
+	if term, _ := c.nodes[leader].State(); term < 2 {
+		t.Fatalf("term went backwards after restart: %d", term)
+	}
+	if _, err := c.nodes[leader].Propose(context.Background(), []byte("b")); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range c.states {
+		waitFor(t, time.Second, func() bool { return slices.Equal(s.applied(), []string{"a", "b"}) })
+	}
+}
+
+func TestRaftSnapshotCatchUp(t *testing.T) {
+	c := newRaftCluster(t, 3, 3)
+	leader := c.leader(t)
+	var lagging string
+	for _, peer := range c.peers {
+		if peer != leader {
+			lagging = peer
+			break
+		}
+	}
+
+	// 落后的节点错过的日志在领导者上被压缩，只能通过快照追上
+	c.net.isolate(lagging, true)
+	var want []string
+	for i := 0; i < 10; i++ {
+		cmd := fmt.Sprintf("cmd%d", i)
+		want = append(want, cmd)
+		if _, err := c.nodes[leader].Propose(context.Background(), []byte(cmd)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c.net.isolate(lagging, false)
+	waitFor(t, 2*time.Second, func() bool { return slices.Equal(c.states[lagging].applied(), want) })
+
+	// 从快照和剩余日志重启后状态不变
+	c.nodes[lagging].Stop()
+	c.start(t, lagging, c.peers)
+	waitFor(t, 2*time.Second, func() bool { return slices.Equal(c.states[lagging].applied(), want) })
+}
+
+func TestRaftMembershipChange(t *testing.T) {
+	c := newRaftCluster(t, 3, 2)
+	leader := c.leader(t)
+	if _, err := c.nodes[leader].Propose(context.Background(), []byte("before")); err != nil {
+		t.Fatal(err)
+	}
+
+	// 新节点以加入者身份启动，通过任意已有节点（非领导者会转发）加入集群
+	var follower string
+	for _, peer := range c.peers {
+		if peer != leader {
+			follower = peer
+			break
+		}
+	}
+	// 提交只需要多数派确认，等待选中的跟随者也收到领导者的心跳，才能转发加入请求
+	waitFor(t, 2*time.Second, func() bool { return c.nodes[follower].Leader() == leader })
+	c.start(t, "node3", nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := c.nodes["node3"].Join(ctx, follower, 2); err != nil {
+		t.Fatalf("join: %v", err)
+	}
+	all := []string{"node0", "node1", "node2", "node3"}
+	for peer, s := range c.states {
+		waitFor(t, 2*time.Second, func() bool { return slices.Equal(s.appliedMembers(), all) })
+		if members := c.nodes[peer].Members(); len(members) != 4 {
+			t.Fatalf("%s has members %v", peer, members)
+		}
+	}
+	// 新节点通过日志或快照获得了之前的命令
+	waitFor(t, 2*time.Second, func() bool { return slices.Contains(c.states["node3"].applied(), "before") })
+
+	// 移除领导者自己：它在变更提交后退位，剩下的节点选出新领导者
+	if _, err := c.nodes[leader].RemoveMember(ctx, leader); err != nil {
+		t.Fatalf("remove leader: %v", err)
+	}
+	next := c.leader(t, leader)
+	for peer, s := range c.states {
+		if peer != leader {
+			waitFor(t, 2*time.Second, func() bool { return len(s.appliedMembers()) == 3 && !slices.Contains(s.appliedMembers(), leader) })
+		}
+	}
+	if _, err := c.nodes[next].Propose(ctx, []byte("after")); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, 2*time.Second, func() bool { return slices.Contains(c.states["node3"].applied(), "after") })
+	if _, isLeader := c.nodes[leader].State(); isLeader {
+		t.Fatalf("removed leader %s is still leading", leader)
+	}
+}
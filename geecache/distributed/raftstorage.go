@@ -0,0 +1,381 @@
+package distributed
+
+import (
+	"GeeCache/geecache/geecachepb"
+	"GeeCache/geecache/logging"
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// HardState 是 Raft 在响应任何请求之前必须持久化的状态
+type HardState struct {
+	Term     int32
+	VotedFor string
+}
+
+// RaftStorage 持久化 Raft 的硬状态、日志和快照。所有写方法返回前数据必须已经落盘。
+type RaftStorage interface {
+	// Load 返回上次保存的硬状态、快照（没有时为 nil）以及快照之后的日志
+	Load() (HardState, *geecachepb.Snapshot, []*geecachepb.LogEntry, error)
+	// SaveHardState 保存当前任期和投票对象
+	SaveHardState(state HardState) error
+	// Append 保存 entries，索引不小于 entries[0].Index 的旧日志会被覆盖
+	Append(entries []*geecachepb.LogEntry) error
+	// SaveSnapshot 保存快照，并把快照之后的日志替换为 entries
+	SaveSnapshot(snapshot *geecachepb.Snapshot, entries []*geecachepb.LogEntry) error
+}
+
+// raftLog 是两种存储共用的内存日志，按索引覆盖
+type raftLog struct {
+	state    HardState
+	snapshot *geecachepb.Snapshot
+	entries  []*geecachepb.LogEntry
+}
+
+func (l *raftLog) append(entries []*geecachepb.LogEntry) {
+	if len(entries) == 0 {
+		return
+	}
+	first := entries[0].Index
+	l.entries = slices.DeleteFunc(l.entries, func(e *geecachepb.LogEntry) bool { return e.Index >= first })
+	l.entries = append(l.entries, entries...)
+}
+
+func (l *raftLog) load() (HardState, *geecachepb.Snapshot, []*geecachepb.LogEntry, error) {
+	return l.state, l.snapshot, slices.Clone(l.entries), nil
+}
+
+// MemoryStorage 把状态保存在内存中，进程退出后丢失，适合测试和不需要持久化的场景
+type MemoryStorage struct {
+	mu  sync.Mutex
+	log raftLog
+}
+
+// NewMemoryStorage 创建一个空的内存存储
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{}
+}
+
+func (s *MemoryStorage) Load() (HardState, *geecachepb.Snapshot, []*geecachepb.LogEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.log.load()
+}
+
+func (s *MemoryStorage) SaveHardState(state HardState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.log.state = state
+	return nil
+}
+
+func (s *MemoryStorage) Append(entries []*geecachepb.LogEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.log.append(entries)
+	return nil
+}
+
+func (s *MemoryStorage) SaveSnapshot(snapshot *geecachepb.Snapshot, entries []*geecachepb.LogEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.log.snapshot = snapshot
+	s.log.entries = slices.Clone(entries)
+	return nil
+}
+
+// WAL 记录类型
+const (
+	recordHardState byte = iota + 1
+	recordEntry
+)
+
+const (
+	walFile         = "raft.wal"
+	snapshotFile    = "raft.snapshot"
+	walHeaderLength = 9 // 4 字节长度 + 4 字节 CRC32 + 1 字节类型
+)
+
+// FileStorage 把硬状态和日志写入目录下的 WAL 文件，每次写入后 fsync；快照单独保存，
+// 保存快照时重写 WAL，只保留快照之后的日志。
+//
+// WAL 中每条记录的格式为：长度(4) | CRC32(4) | 类型(1) | 内容，CRC 覆盖类型和内容。
+// 写入中途崩溃留下的不完整记录会在下次打开时被截断。
+type FileStorage struct {
+	mu  sync.Mutex
+	dir string
+	wal *os.File
+	log raftLog // 与磁盘内容一致的内存副本，重写 WAL 时使用
+}
+
+// OpenFileStorage 打开（不存在时创建）dir 下的 Raft 存储并回放 WAL
+func OpenFileStorage(dir string) (*FileStorage, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	s := &FileStorage{dir: dir}
+	if err := s.loadSnapshot(); err != nil {
+		return nil, err
+	}
+	wal, err := os.OpenFile(filepath.Join(dir, walFile), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	s.wal = wal
+	if err := s.replay(); err != nil {
+		wal.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileStorage) loadSnapshot() error {
+	b, err := os.ReadFile(filepath.Join(s.dir, snapshotFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	snapshot := &geecachepb.Snapshot{}
+	if err := proto.Unmarshal(b, snapshot); err != nil {
+		return fmt.Errorf("corrupt raft snapshot: %w", err)
+	}
+	s.log.snapshot = snapshot
+	return nil
+}
+
+// replay 读取 WAL 中的所有记录，遇到不完整或损坏的记录时从该位置截断
+func (s *FileStorage) replay() error {
+	info, err := s.wal.Stat()
+	if err != nil {
+		return err
+	}
+	r := bufio.NewReader(s.wal)
+	var offset int64
+	for {
+		typ, payload, err := readRecord(r, info.Size()-offset)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			logging.Default().Warn("truncating raft wal", "dir", s.dir, "offset", offset, "error", err)
+			if err := s.wal.Truncate(offset); err != nil {
+				return err
+			}
+			break
+		}
+		offset += walHeaderLength + int64(len(payload))
+
+		switch typ {
+		case recordHardState:
+			s.log.state = decodeHardState(payload)
+		case recordEntry:
+			entry := &geecachepb.LogEntry{}
+			if err := proto.Unmarshal(payload, entry); err != nil {
+				return fmt.Errorf("corrupt raft log entry at offset %d: %w", offset, err)
+			}
+			s.log.append([]*geecachepb.LogEntry{entry})
+		}
+	}
+	// 重写 WAL 前崩溃时，WAL 中可能仍有已经包含在快照中的日志
+	if snapshot := s.log.snapshot; snapshot != nil {
+		s.log.entries = slices.DeleteFunc(s.log.entries, func(e *geecachepb.LogEntry) bool {
+			return e.Index <= snapshot.LastIndex
+		})
+	}
+	_, err = s.wal.Seek(offset, io.SeekStart)
+	return err
+}
+
+// readRecord 读取一条记录，remaining 为文件中从这条记录开始剩余的字节数。
+// 长度字段不在 CRC 的覆盖范围内，写坏的长度超出 remaining 时按不完整的记录处理，而不是按它分配内存。
+func readRecord(r io.Reader, remaining int64) (byte, []byte, error) {
+	var header [walHeaderLength]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if err == io.EOF {
+			return 0, nil, io.EOF
+		}
+		return 0, nil, fmt.Errorf("short record header: %w", err)
+	}
+	length := binary.BigEndian.Uint32(header[0:4])
+	if int64(length) > remaining-walHeaderLength {
+		return 0, nil, fmt.Errorf("record length %d exceeds the remaining %d bytes", length, remaining-walHeaderLength)
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, fmt.Errorf("short record: %w", err)
+	}
+	crc := crc32.NewIEEE()
+	crc.Write(header[8:9])
+	crc.Write(payload)
+	if crc.Sum32() != binary.BigEndian.Uint32(header[4:8]) {
+		return 0, nil, errors.New("checksum mismatch")
+	}
+	return header[8], payload, nil
+}
+
+func appendRecord(buf []byte, typ byte, payload []byte) []byte {
+	crc := crc32.NewIEEE()
+	crc.Write([]byte{typ})
+	crc.Write(payload)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(payload)))
+	buf = binary.BigEndian.AppendUint32(buf, crc.Sum32())
+	buf = append(buf, typ)
+	return append(buf, payload...)
+}
+
+func encodeHardState(state HardState) []byte {
+	return append(binary.BigEndian.AppendUint32(nil, uint32(state.Term)), state.VotedFor...)
+}
+
+func decodeHardState(b []byte) HardState {
+	if len(b) < 4 {
+		return HardState{}
+	}
+	return HardState{Term: int32(binary.BigEndian.Uint32(b)), VotedFor: string(b[4:])}
+}
+
+func encodeEntries(buf []byte, entries []*geecachepb.LogEntry) ([]byte, error) {
+	for _, entry := range entries {
+		payload, err := proto.Marshal(entry)
+		if err != nil {
+			return nil, err
+		}
+		buf = appendRecord(buf, recordEntry, payload)
+	}
+	return buf, nil
+}
+
+// write 追加记录并 fsync
+func (s *FileStorage) write(buf []byte) error {
+	if _, err := s.wal.Write(buf); err != nil {
+		return err
+	}
+	return s.wal.Sync()
+}
+
+func (s *FileStorage) Load() (HardState, *geecachepb.Snapshot, []*geecachepb.LogEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.log.load()
+}
+
+func (s *FileStorage) SaveHardState(state HardState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.write(appendRecord(nil, recordHardState, encodeHardState(state))); err != nil {
+		return err
+	}
+	s.log.state = state
+	return nil
+}
+
+func (s *FileStorage) Append(entries []*geecachepb.LogEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	buf, err := encodeEntries(nil, entries)
+	if err != nil {
+		return err
+	}
+	if err := s.write(buf); err != nil {
+		return err
+	}
+	s.log.append(entries)
+	return nil
+}
+
+// SaveSnapshot 先原子地替换快照文件，再用硬状态和 entries 重写 WAL。
+// 重写 WAL 失败时重新打开 WAL 文件，之后的写入仍然可以继续。
+func (s *FileStorage) SaveSnapshot(snapshot *geecachepb.Snapshot, entries []*geecachepb.LogEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, err := proto.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	if err := s.replaceFile(snapshotFile, b); err != nil {
+		return err
+	}
+	s.log.snapshot = snapshot
+
+	buf := appendRecord(nil, recordHardState, encodeHardState(s.log.state))
+	if buf, err = encodeEntries(buf, entries); err != nil {
+		return err
+	}
+	if err := s.wal.Close(); err != nil {
+		return errors.Join(err, s.reopenWAL())
+	}
+	if err := s.replaceFile(walFile, buf); err != nil {
+		return errors.Join(err, s.reopenWAL())
+	}
+	if err := s.reopenWAL(); err != nil {
+		return err
+	}
+	s.log.entries = slices.Clone(entries)
+	return nil
+}
+
+// reopenWAL 以追加方式重新打开 WAL 文件，打开失败时保留原来的文件句柄
+func (s *FileStorage) reopenWAL() error {
+	wal, err := os.OpenFile(filepath.Join(s.dir, walFile), os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	s.wal = wal
+	return nil
+}
+
+// replaceFile 写入临时文件并 fsync 后重命名为 name，再 fsync 目录，保证崩溃后要么是旧文件要么是新文件
+func (s *FileStorage) replaceFile(name string, data []byte) error {
+	tmp := filepath.Join(s.dir, name+".tmp")
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(s.dir, name)); err != nil {
+		return err
+	}
+	dir, err := os.Open(s.dir)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
+
+// Close 关闭 WAL 文件
+func (s *FileStorage) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.wal.Close()
+}
+
+var (
+	_ RaftStorage = (*MemoryStorage)(nil)
+	_ RaftStorage = (*FileStorage)(nil)
+)
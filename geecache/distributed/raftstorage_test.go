@@ -0,0 +1,163 @@
+package distributed_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"GeeCache/geecache/distributed"
+	"GeeCache/geecache/geecachepb"
+)
+
+func entries(term int32, from, to int64) []*geecachepb.LogEntry {
+	var es []*geecachepb.LogEntry
+	for i := from; i <= to; i++ {
+		es = append(es, &geecachepb.LogEntry{Index: i, Term: term, Command: []byte{byte(i)}})
+	}
+	return es
+}
+
+func termsOf(es []*geecachepb.LogEntry) (terms []int32) {
+	for _, e := range es {
+		terms = append(terms, e.Term)
+	}
+	return terms
+}
+
+func TestFileStorage(t *testing.T) {
+	dir := t.TempDir()
+	s, err := distributed.OpenFileStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SaveHardState(distributed.HardState{Term: 3, VotedFor: "a:8001"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Append(entries(1, 1, 5)); err != nil {
+		t.Fatal(err)
+	}
+	// 覆盖 4、5 两条日志
+	if err := s.Append(entries(2, 4, 6)); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	// 模拟写入中途崩溃留下的半条记录
+	f, err := os.OpenFile(filepath.Join(dir, "raft.wal"), os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte{0, 0, 0, 9, 1, 2})
+	f.Close()
+
+	s, err = distributed.OpenFileStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, snapshot, es, _ := s.Load()
+	if state.Term != 3 || state.VotedFor != "a:8001" || snapshot != nil {
+		t.Fatalf("unexpected state %+v, snapshot %v", state, snapshot)
+	}
+	if terms := termsOf(es); len(es) != 6 || es[5].Index != 6 || terms[2] != 1 || terms[3] != 2 {
+		t.Fatalf("unexpected entries after replay: terms %v", terms)
+	}
+	// 截断后可以继续追加
+	if err := s.Append(entries(2, 7, 7)); err != nil {
+		t.Fatal(err)
+	}
+
+	// 保存快照后只保留快照之后的日志
+	_, _, es, _ = s.Load()
+	if err := s.SaveSnapshot(&geecachepb.Snapshot{LastIndex: 5, LastTerm: 2, Data: []byte("state")}, es[5:]); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Append(entries(3, 8, 8)); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	s, err = distributed.OpenFileStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	state, snapshot, es, _ = s.Load()
+	if state.Term != 3 || snapshot.GetLastIndex() != 5 || string(snapshot.GetData()) != "state" {
+		t.Fatalf("unexpected state %+v, snapshot %v", state, snapshot)
+	}
+	if len(es) != 3 || es[0].Index != 6 || es[2].Index != 8 || es[2].Term != 3 {
+		t.Fatalf("unexpected entries after snapshot: %v", es)
+	}
+}
+
+func TestFileStorageCorruptRecordLength(t *testing.T) {
+	dir := t.TempDir()
+	s, err := distributed.OpenFileStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Append(entries(1, 1, 3)); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	// 长度字段被写坏，不能按它分配内存
+	f, err := os.OpenFile(filepath.Join(dir, "raft.wal"), os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte{0xff, 0xff, 0xff, 0xf0, 0, 0, 0, 0, 2, 1, 2, 3})
+	f.Close()
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	s, err = distributed.OpenFileStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runtime.ReadMemStats(&after)
+	defer s.Close()
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 1<<20 {
+		t.Fatalf("replay allocated %d bytes for a corrupt record length", allocated)
+	}
+	if _, _, es, _ := s.Load(); len(es) != 3 {
+		t.Fatalf("got %d entries after replay, want 3", len(es))
+	}
+	if err := s.Append(entries(1, 4, 4)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileStorageSaveSnapshotFailureKeepsWAL(t *testing.T) {
+	dir := t.TempDir()
+	s, err := distributed.OpenFileStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Append(entries(1, 1, 3)); err != nil {
+		t.Fatal(err)
+	}
+	// 临时文件的位置被目录占用，重写 WAL 会失败
+	if err := os.Mkdir(filepath.Join(dir, "raft.wal.tmp"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SaveSnapshot(&geecachepb.Snapshot{LastIndex: 2, LastTerm: 1}, entries(1, 3, 3)); err == nil {
+		t.Fatal("SaveSnapshot succeeded, want an error")
+	}
+	// WAL 仍然可以写入
+	if err := s.Append(entries(1, 4, 4)); err != nil {
+		t.Fatalf("Append after a failed SaveSnapshot: %v", err)
+	}
+	s.Close()
+
+	s, err = distributed.OpenFileStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	_, snapshot, es, _ := s.Load()
+	if snapshot.GetLastIndex() != 2 || len(es) != 2 || es[0].Index != 3 || es[1].Index != 4 {
+		t.Fatalf("unexpected snapshot %v and entries %v after reopening", snapshot, es)
+	}
+}
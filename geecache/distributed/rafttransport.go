@@ -0,0 +1,100 @@
+package distributed
+
+import (
+	"GeeCache/geecache/geecachepb"
+	"context"
+	"fmt"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// RaftTransport 负责把 Raft 请求发送到其他节点
+type RaftTransport interface {
+	RequestVote(ctx context.Context, peer string, req *geecachepb.RequestVoteRequest) (*geecachepb.RequestVoteResponse, error)
+	AppendEntries(ctx context.Context, peer string, req *geecachepb.AppendEntriesRequest) (*geecachepb.AppendEntriesResponse, error)
+	InstallSnapshot(ctx context.Context, peer string, req *geecachepb.InstallSnapshotRequest) (*geecachepb.InstallSnapshotResponse, error)
+	// ChangeMembership 把成员变更请求转发给 peer，用于加入集群或转发给领导者
+	ChangeMembership(ctx context.Context, peer string, req *geecachepb.MembershipRequest) (*geecachepb.MembershipResponse, error)
+}
+
+// GRPCTransport 通过 GroupCache gRPC 服务发送 Raft 请求，每个节点复用同一个连接
+type GRPCTransport struct {
+	mu       sync.Mutex
+	dialOpts []grpc.DialOption
+	conns    map[string]*grpc.ClientConn
+}
+
+// NewGRPCTransport 创建 gRPC 传输层，opts 会追加在默认的不加密连接选项之后
+func NewGRPCTransport(opts ...grpc.DialOption) *GRPCTransport {
+	return &GRPCTransport{
+		dialOpts: append([]grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}, opts...),
+		conns:    make(map[string]*grpc.ClientConn),
+	}
+}
+
+func (t *GRPCTransport) client(peer string) (geecachepb.GroupCacheClient, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	conn, ok := t.conns[peer]
+	if !ok {
+		var err error
+		conn, err = grpc.DialContext(context.Background(), peer, t.dialOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to peer %s: %v", peer, err)
+		}
+		t.conns[peer] = conn
+	}
+	return geecachepb.NewGroupCacheClient(conn), nil
+}
+
+// RequestVote 向 peer 发送投票请求
+func (t *GRPCTransport) RequestVote(ctx context.Context, peer string, req *geecachepb.RequestVoteRequest) (*geecachepb.RequestVoteResponse, error) {
+	client, err := t.client(peer)
+	if err != nil {
+		return nil, err
+	}
+	return client.RequestVote(ctx, req)
+}
+
+// AppendEntries 向 peer 发送日志复制或心跳请求
+func (t *GRPCTransport) AppendEntries(ctx context.Context, peer string, req *geecachepb.AppendEntriesRequest) (*geecachepb.AppendEntriesResponse, error) {
+	client, err := t.client(peer)
+	if err != nil {
+		return nil, err
+	}
+	return client.AppendEntries(ctx, req)
+}
+
+// InstallSnapshot 向 peer 发送快照
+func (t *GRPCTransport) InstallSnapshot(ctx context.Context, peer string, req *geecachepb.InstallSnapshotRequest) (*geecachepb.InstallSnapshotResponse, error) {
+	client, err := t.client(peer)
+	if err != nil {
+		return nil, err
+	}
+	return client.InstallSnapshot(ctx, req)
+}
+
+// ChangeMembership 向 peer 发送成员变更请求
+func (t *GRPCTransport) ChangeMembership(ctx context.Context, peer string, req *geecachepb.MembershipRequest) (*geecachepb.MembershipResponse, error) {
+	client, err := t.client(peer)
+	if err != nil {
+		return nil, err
+	}
+	return client.ChangeMembership(ctx, req)
+}
+
+// Close 关闭所有连接
+func (t *GRPCTransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var err error
+	for peer, conn := range t.conns {
+		if cerr := conn.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+		delete(t.conns, peer)
+	}
+	return err
+}
@@ -0,0 +1,129 @@
+package distributed
+
+import (
+	"hash/fnv"
+	"math"
+	"sort"
+)
+
+// Hash64 maps bytes to uint64
+type Hash64 func(data []byte) uint64
+
+// Rendezvous 实现最高随机权重（HRW）哈希：对每个节点计算 hash(node, key)，得分最高的节点负责该 key。
+// 增删节点时只有属于该节点的 key 会迁移，不需要虚拟节点，但每次查找需要遍历所有节点。
+type Rendezvous struct {
+	hash    Hash64
+	nodes   []string
+	weights map[string]int
+}
+
+// NewRendezvous 创建一个 Rendezvous 实例，fn 为 nil 时使用 FNV-1a
+func NewRendezvous(fn Hash64) *Rendezvous {
+	if fn == nil {
+		fn = func(data []byte) uint64 {
+			h := fnv.New64a()
+			h.Write(data)
+			return h.Sum64()
+		}
+	}
+	return &Rendezvous{hash: fn, weights: make(map[string]int)}
+}
+
+// Add 加入权重为 1 的节点
+func (r *Rendezvous) Add(nodes ...string) {
+	for _, node := range nodes {
+		r.addNode(node, 1)
+	}
+}
+
+// AddWeighted 按权重加入节点，已存在的节点只更新权重
+func (r *Rendezvous) AddWeighted(weights map[string]int) {
+	for node, weight := range weights {
+		r.addNode(node, weight)
+	}
+}
+
+func (r *Rendezvous) addNode(node string, weight int) {
+	if weight < 1 {
+		weight = 1
+	}
+	if _, ok := r.weights[node]; !ok {
+		r.nodes = append(r.nodes, node)
+	}
+	r.weights[node] = weight
+}
+
+// Remove 删除节点
+func (r *Rendezvous) Remove(nodes ...string) {
+	for _, node := range nodes {
+		if _, ok := r.weights[node]; !ok {
+			continue
+		}
+		delete(r.weights, node)
+		for i, n := range r.nodes {
+			if n == node {
+				r.nodes = append(r.nodes[:i], r.nodes[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// Weight 返回节点的权重，节点不存在时返回 0
+func (r *Rendezvous) Weight(node string) int {
+	return r.weights[node]
+}
+
+// score 使用对数法计算加权得分 -w/ln(u)，u 为 (0,1) 上均匀分布的哈希值，
+// 这样节点被选中的概率与权重成正比。
+func (r *Rendezvous) score(node, key string) float64 {
+	h := mix64(r.hash([]byte(node + "\x00" + key)))
+	u := (float64(h>>11) + 0.5) / (1 << 53)
+	return -float64(r.weights[node]) / math.Log(u)
+}
+
+// mix64 是 splitmix64 的收尾步骤。FNV 等哈希在输入只有末尾不同时高位变化很小，
+// 打散后再取高位才能得到均匀的 u。
+func mix64(h uint64) uint64 {
+	h ^= h >> 30
+	h *= 0xbf58476d1ce4e5b9
+	h ^= h >> 27
+	h *= 0x94d049bb133111eb
+	h ^= h >> 31
+	return h
+}
+
+// Get 返回得分最高的节点
+func (r *Rendezvous) Get(key string) string {
+	best, bestScore := "", math.Inf(-1)
+	for _, node := range r.nodes {
+		if s := r.score(node, key); s > bestScore {
+			best, bestScore = node, s
+		}
+	}
+	return best
+}
+
+// GetMultipleNodes 按得分从高到低返回 n 个不同的节点
+func (r *Rendezvous) GetMultipleNodes(key string, n int) []string {
+	if n > len(r.nodes) {
+		n = len(r.nodes)
+	}
+	if n <= 0 {
+		return nil
+	}
+	nodes := append([]string(nil), r.nodes...)
+	scores := make(map[string]float64, len(nodes))
+	for _, node := range nodes {
+		scores[node] = r.score(node, key)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return scores[nodes[i]] > scores[nodes[j]]
+	})
+	return nodes[:n]
+}
+
+// Nodes 返回所有节点
+func (r *Rendezvous) Nodes() []string {
+	return append([]string(nil), r.nodes...)
+}
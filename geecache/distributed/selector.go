@@ -0,0 +1,58 @@
+package distributed
+
+import "slices"
+
+// PeerSelector 决定 key 由哪个节点负责，GRPCPool 通过它选择节点。
+// 一致性哈希环 Map、Rendezvous 和 JumpHash 都实现了该接口。实现不需要并发安全，由 GRPCPool 加锁。
+type PeerSelector interface {
+	Add(nodes ...string)
+	Remove(nodes ...string)
+	// Get 返回负责 key 的节点，没有节点时返回空字符串
+	Get(key string) string
+	// GetMultipleNodes 按优先顺序返回负责 key 的多个节点，第一个与 Get 相同
+	GetMultipleNodes(key string, n int) []string
+	// Nodes 返回所有节点
+	Nodes() []string
+}
+
+// WeightedSelector 是支持节点权重的 PeerSelector
+type WeightedSelector interface {
+	PeerSelector
+	AddWeighted(weights map[string]int)
+	Weight(node string) int
+}
+
+// LoadBoundedSelector 是支持有界负载的 PeerSelector
+type LoadBoundedSelector interface {
+	PeerSelector
+	SetLoadBound(epsilon float64)
+	GetLeast(key string) string
+	Inc(node string)
+	Done(node string)
+}
+
+// ReplicaNodes 按优先顺序返回负责 key 的 n 个不同节点，并跳过 exclude 中的节点（通常是本节点）。
+// 被跳过的节点由后续节点补上，因此节点足够时总能返回 n 个。
+func ReplicaNodes(s PeerSelector, key string, n int, exclude ...string) []string {
+	if n <= 0 {
+		return nil
+	}
+	candidates := s.GetMultipleNodes(key, n+len(exclude))
+	nodes := make([]string, 0, n)
+	for _, node := range candidates {
+		if len(nodes) == n {
+			break
+		}
+		if !slices.Contains(exclude, node) {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+var (
+	_ WeightedSelector    = (*Map)(nil)
+	_ LoadBoundedSelector = (*Map)(nil)
+	_ WeightedSelector    = (*Rendezvous)(nil)
+	_ PeerSelector        = (*JumpHash)(nil)
+)
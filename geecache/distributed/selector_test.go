@@ -0,0 +1,157 @@
+package distributed
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+const (
+	selectorNodes = 10
+	selectorKeys  = 100000
+)
+
+// selectors 列出参与对比的节点选择算法
+var selectors = map[string]func() PeerSelector{
+	"ring":       func() PeerSelector { return New(defaultReplicas, nil) },
+	"rendezvous": func() PeerSelector { return NewRendezvous(nil) },
+	"jump":       func() PeerSelector { return NewJumpHash(nil) },
+}
+
+func selectorNodeNames(n int) []string {
+	nodes := make([]string, n)
+	for i := range nodes {
+		nodes[i] = fmt.Sprintf("10.0.0.%d:8001", i)
+	}
+	return nodes
+}
+
+// assign 返回每个 key 当前的负责节点
+func assign(s PeerSelector, keys int) []string {
+	owners := make([]string, keys)
+	for i := range owners {
+		owners[i] = s.Get("key" + strconv.Itoa(i))
+	}
+	return owners
+}
+
+// relStddev 返回各节点 key 数量的相对标准差，越小分布越均匀
+func relStddev(owners []string, nodes []string) float64 {
+	counts := make(map[string]int, len(nodes))
+	for _, o := range owners {
+		counts[o]++
+	}
+	mean := float64(len(owners)) / float64(len(nodes))
+	var sum float64
+	for _, n := range nodes {
+		d := float64(counts[n]) - mean
+		sum += d * d
+	}
+	return math.Sqrt(sum/float64(len(nodes))) / mean
+}
+
+// remapped 返回负责节点发生变化的 key 所占比例
+func remapped(before, after []string) float64 {
+	moved := 0
+	for i := range before {
+		if before[i] != after[i] {
+			moved++
+		}
+	}
+	return float64(moved) / float64(len(before))
+}
+
+func TestSelectors(t *testing.T) {
+	nodes := selectorNodeNames(selectorNodes)
+	for name, newSelector := range selectors {
+		t.Run(name, func(t *testing.T) {
+			s := newSelector()
+			if got := s.Get("key"); got != "" {
+				t.Fatalf("empty selector returned %q", got)
+			}
+			s.Add(nodes...)
+			if len(s.Nodes()) != selectorNodes {
+				t.Fatalf("expected %d nodes, got %v", selectorNodes, s.Nodes())
+			}
+
+			before := assign(s, selectorKeys)
+			if dev := relStddev(before, nodes); dev > 0.3 {
+				t.Errorf("distribution too skewed: relative stddev %.3f", dev)
+			}
+			for i := 0; i < 100; i++ {
+				key := "key" + strconv.Itoa(i)
+				replicas := s.GetMultipleNodes(key, 3)
+				if len(replicas) != 3 || replicas[0] != before[i] {
+					t.Fatalf("GetMultipleNodes(%q) = %v, Get = %q", key, replicas, before[i])
+				}
+				if replicas[0] == replicas[1] || replicas[1] == replicas[2] || replicas[0] == replicas[2] {
+					t.Fatalf("GetMultipleNodes(%q) returned duplicates: %v", key, replicas)
+				}
+				if others := ReplicaNodes(s, key, 3, before[i]); len(others) != 3 || slices.Contains(others, before[i]) {
+					t.Fatalf("ReplicaNodes(%q) excluding %s = %v", key, before[i], others)
+				}
+			}
+
+			// 增加一个节点后，只有约 1/(n+1) 的 key 应迁移，且只能迁到新节点。
+			// JumpHash 按节点名分配桶号，新节点的名字排在最后才满足这一点
+			extra := "10.0.1.0:8001"
+			s.Add(extra)
+			after := assign(s, selectorKeys)
+			for i := range before {
+				if before[i] != after[i] && after[i] != extra {
+					t.Fatalf("key%d moved from %s to %s, not the new node", i, before[i], after[i])
+				}
+			}
+			if frac := remapped(before, after); frac > 2.0/float64(selectorNodes+1) {
+				t.Errorf("adding a node remapped %.3f of keys", frac)
+			}
+
+			// 删除该节点后恢复原来的分布
+			s.Remove(extra)
+			if frac := remapped(before, assign(s, selectorKeys)); frac != 0 {
+				t.Errorf("removing the added node left %.3f of keys remapped", frac)
+			}
+		})
+	}
+}
+
+func BenchmarkSelectorGet(b *testing.B) {
+	nodes := selectorNodeNames(selectorNodes)
+	for name, newSelector := range selectors {
+		b.Run(name, func(b *testing.B) {
+			s := newSelector()
+			s.Add(nodes...)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				s.Get("key" + strconv.Itoa(i))
+			}
+		})
+	}
+}
+
+// BenchmarkSelectorDistribution 报告各算法的分布均匀度以及增删节点时迁移的 key 比例
+func BenchmarkSelectorDistribution(b *testing.B) {
+	nodes := selectorNodeNames(selectorNodes)
+	for name, newSelector := range selectors {
+		b.Run(name, func(b *testing.B) {
+			var dev, added, removed float64
+			for i := 0; i < b.N; i++ {
+				s := newSelector()
+				s.Add(nodes...)
+				before := assign(s, selectorKeys)
+				dev = relStddev(before, nodes)
+
+				s.Add("10.0.0.99:8001")
+				added = remapped(before, assign(s, selectorKeys))
+
+				s.Remove("10.0.0.99:8001", nodes[0])
+				removed = remapped(before, assign(s, selectorKeys))
+			}
+			b.ReportMetric(dev, "rel-stddev")
+			b.ReportMetric(added, "remap-add")
+			b.ReportMetric(removed, "remap-remove")
+		})
+	}
+}
@@ -1,5 +1,10 @@
 package interfaces
 
+import (
+	"context"
+	"time"
+)
+
 // A Getter loads data for a key
 type Getter interface {
 	Get(key string) ([]byte, error)
@@ -14,3 +19,73 @@ type GetterFunc func(key string) ([]byte, error)
 func (f GetterFunc) Get(key string) ([]byte, error) {
 	return f(key)
 }
+
+// A TTLGetter loads data for a key along with its time-to-live.
+// 返回的 ttl <= 0 时使用 Group 的默认 TTL。
+type TTLGetter interface {
+	GetWithTTL(key string) ([]byte, time.Duration, error)
+}
+
+// A TTLGetterFunc implements TTLGetter with a function
+type TTLGetterFunc func(key string) ([]byte, time.Duration, error)
+
+// Get implements Getter interface function，忽略 ttl
+func (f TTLGetterFunc) Get(key string) ([]byte, error) {
+	b, _, err := f(key)
+	return b, err
+}
+
+// GetWithTTL implements TTLGetter interface function
+func (f TTLGetterFunc) GetWithTTL(key string) ([]byte, time.Duration, error) {
+	return f(key)
+}
+
+// A GetterWithContext loads data for a key, honouring the deadline and
+// cancellation of ctx. Group 优先使用该接口调用回调函数。
+type GetterWithContext interface {
+	GetContext(ctx context.Context, key string) ([]byte, error)
+}
+
+// A GetterWithContextFunc implements GetterWithContext with a function
+type GetterWithContextFunc func(ctx context.Context, key string) ([]byte, error)
+
+// Get implements Getter interface function，使用 context.Background()
+func (f GetterWithContextFunc) Get(key string) ([]byte, error) {
+	return f(context.Background(), key)
+}
+
+// GetContext implements GetterWithContext interface function
+func (f GetterWithContextFunc) GetContext(ctx context.Context, key string) ([]byte, error) {
+	return f(ctx, key)
+}
+
+// A TTLGetterWithContext loads data for a key along with its time-to-live,
+// honouring the deadline and cancellation of ctx. Group 优先使用该接口，其次是 GetterWithContext 和 TTLGetter。
+type TTLGetterWithContext interface {
+	GetWithTTLContext(ctx context.Context, key string) ([]byte, time.Duration, error)
+}
+
+// A TTLGetterWithContextFunc implements TTLGetterWithContext with a function
+type TTLGetterWithContextFunc func(ctx context.Context, key string) ([]byte, time.Duration, error)
+
+// Get implements Getter interface function，使用 context.Background() 并忽略 ttl
+func (f TTLGetterWithContextFunc) Get(key string) ([]byte, error) {
+	b, _, err := f(context.Background(), key)
+	return b, err
+}
+
+// GetContext implements GetterWithContext interface function，忽略 ttl
+func (f TTLGetterWithContextFunc) GetContext(ctx context.Context, key string) ([]byte, error) {
+	b, _, err := f(ctx, key)
+	return b, err
+}
+
+// GetWithTTL implements TTLGetter interface function，使用 context.Background()
+func (f TTLGetterWithContextFunc) GetWithTTL(key string) ([]byte, time.Duration, error) {
+	return f(context.Background(), key)
+}
+
+// GetWithTTLContext implements TTLGetterWithContext interface function
+func (f TTLGetterWithContextFunc) GetWithTTLContext(ctx context.Context, key string) ([]byte, time.Duration, error) {
+	return f(ctx, key)
+}
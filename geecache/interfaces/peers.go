@@ -4,6 +4,7 @@ package interfaces
 
 import (
 	pb "GeeCache/geecache/geecachepb" // 新的 geecachepb 包路径
+	"context"
 )
 
 // PeerPicker is the interface that must be implement to locate
@@ -16,11 +17,17 @@ type PeerPicker interface {
 // PeerGetter is the interface that must be implemented by a peer
 // 从对应 group 查找缓存值。
 // PeerGetter 就对应于上述流程中的 HTTP 客户端。
+// ctx 的截止时间和取消信号会随 gRPC 调用传递到远程节点。
 type PeerGetter interface {
-	Get(in *pb.Request, out *pb.Response) error
+	Get(ctx context.Context, in *pb.Request, out *pb.Response) error
+	Delete(ctx context.Context, in *pb.Request, out *pb.DeleteResponse) error // 删除远程节点上的缓存
+	Push(ctx context.Context, in *pb.PushRequest, out *pb.PushResponse) error // 把热点数据写入远程节点
 }
 
 // 定义接口用于获取多个副本
+// GetReplicatedPeers 按优先顺序返回至多 replicas 个不同的副本节点，不包含本节点；
+// 热点 key 的读请求通过 SelectPeer 分散到各个副本上
 type ReplicatedPeerPicker interface {
 	GetReplicatedPeers(key string, replicas int) []PeerGetter
+	SelectPeer(peers []PeerGetter) PeerGetter
 }
@@ -2,6 +2,7 @@ package interfaces
 
 import (
 	"GeeCache/geecache/common"
+	"time"
 )
 
 // // Value represents a value stored in the cache
@@ -11,8 +12,29 @@ import (
 
 // 接口定义
 type EvictionPolicy interface {
-	Get(key string) (value common.Value, ok bool)
+	Get(key string) (value common.Value, ok bool) // 已过期的条目视为不存在
 	Add(key string, value common.Value)
+	AddWithExpire(key string, value common.Value, expire time.Time) // expire 为零值表示永不过期
+	Remove(key string) bool                                         // 删除指定 key，返回该 key 是否存在
 	RemoveOldest()
+	RemoveExpired() int // 清理所有已过期的条目，返回清理的条目数
 	Len() int
+	Bytes() int64 // 当前占用的字节数（key 与 value 的长度之和）
+}
+
+// PolicyInspector 是淘汰策略可选实现的接口，用于管理工具和快照：查看和遍历条目，但不影响淘汰顺序。
+// 所有方法都不更新最近访问时间或访问频率，也不删除条目；已过期的条目视为不存在。
+type PolicyInspector interface {
+	Peek(key string) (value common.Value, ok bool)
+	Contains(key string) bool
+	Keys() []string
+	// Range 对每个条目调用 f，f 返回 false 时停止遍历。f 中不能修改缓存。
+	Range(f func(key string, value common.Value) bool)
+}
+
+// CapacitySetter 是淘汰策略可选实现的接口。ConcurrentCache 以 maxBytes 为 0 创建策略、由 Accountant 限制容量，
+// 创建后通过 SetCapacity 告诉策略分片在预算中的份额。按容量划分内部区域的策略（例如 ARC 的幽灵链表、
+// 2Q 的 A1in 和 A1out、W-TinyLFU 的准入窗口）据此计算各区域的大小，但不会因此主动淘汰。
+type CapacitySetter interface {
+	SetCapacity(bytes int64)
 }
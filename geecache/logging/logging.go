@@ -0,0 +1,125 @@
+// Package logging 提供 GeeCache 各组件共用的 log/slog 日志配置。
+// 组件在创建时从 Default 取得 logger，也可以通过各自的选项为单个节点或 Group 指定 logger。
+// 默认只输出 Warn 及以上级别，并对 Error 以下的重复消息采样，读写路径上的 Debug 日志不会输出。
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var defaultLogger atomic.Pointer[slog.Logger]
+
+func init() {
+	defaultLogger.Store(New(os.Stderr, slog.LevelWarn))
+}
+
+// Default 返回默认 logger。组件在创建时读取它，之后修改只影响新创建的组件。
+func Default() *slog.Logger {
+	return defaultLogger.Load()
+}
+
+// SetDefault 替换默认 logger，l 为 nil 时丢弃所有日志
+func SetDefault(l *slog.Logger) {
+	if l == nil {
+		l = Discard()
+	}
+	defaultLogger.Store(l)
+}
+
+// New 创建输出到 w、最低级别为 level 的文本 logger，Error 以下的重复消息按默认参数采样
+func New(w io.Writer, level slog.Level) *slog.Logger {
+	h := slog.NewTextHandler(w, &slog.HandlerOptions{Level: level})
+	return slog.New(NewSamplingHandler(h, SamplingOptions{}))
+}
+
+// Discard 返回丢弃所有日志的 logger
+func Discard() *slog.Logger {
+	return slog.New(discardHandler{})
+}
+
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (d discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return d }
+func (d discardHandler) WithGroup(string) slog.Handler           { return d }
+
+// SamplingOptions 是采样的参数，零值字段使用默认值
+type SamplingOptions struct {
+	Level      slog.Leveler  // 低于该级别的记录参与采样，nil 时为 slog.LevelError；与 slog.HandlerOptions 一样可以传入 *slog.LevelVar
+	First      int           // 每个周期内同一消息先输出的条数，默认 10
+	Thereafter int           // 之后每隔多少条输出一条，默认 100
+	Tick       time.Duration // 采样周期，默认 1s
+}
+
+// samplingHandler 在每个周期内，同一消息先输出 First 条，之后每 Thereafter 条输出一条。
+// 消息按 Record.Message 区分，因此同一位置的日志无论 key 是什么都共享计数。
+type samplingHandler struct {
+	next    slog.Handler
+	opts    SamplingOptions
+	counter *sampleCounter // 由 WithAttrs/WithGroup 派生的 handler 共享
+}
+
+type sampleCounter struct {
+	mu     sync.Mutex
+	start  time.Time
+	counts map[string]int
+}
+
+// NewSamplingHandler 返回对 next 采样的 handler
+func NewSamplingHandler(next slog.Handler, opts SamplingOptions) slog.Handler {
+	if opts.Level == nil {
+		opts.Level = slog.LevelError
+	}
+	if opts.First <= 0 {
+		opts.First = 10
+	}
+	if opts.Thereafter <= 0 {
+		opts.Thereafter = 100
+	}
+	if opts.Tick <= 0 {
+		opts.Tick = time.Second
+	}
+	return &samplingHandler{next: next, opts: opts, counter: &sampleCounter{counts: make(map[string]int)}}
+}
+
+func (h *samplingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *samplingHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level >= h.opts.Level.Level() || h.counter.allow(r.Message, r.Time, h.opts) {
+		return h.next.Handle(ctx, r)
+	}
+	return nil
+}
+
+func (h *samplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &samplingHandler{next: h.next.WithAttrs(attrs), opts: h.opts, counter: h.counter}
+}
+
+func (h *samplingHandler) WithGroup(name string) slog.Handler {
+	return &samplingHandler{next: h.next.WithGroup(name), opts: h.opts, counter: h.counter}
+}
+
+// allow 判断本周期内第 n 条 msg 是否输出
+func (c *sampleCounter) allow(msg string, now time.Time, opts SamplingOptions) bool {
+	if now.IsZero() {
+		now = time.Now()
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if now.Sub(c.start) >= opts.Tick {
+		c.start = now
+		clear(c.counts)
+	}
+	c.counts[msg]++
+	n := c.counts[msg]
+	return n <= opts.First || (n-opts.First)%opts.Thereafter == 0
+}
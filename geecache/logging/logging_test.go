@@ -0,0 +1,77 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSampling(t *testing.T) {
+	var buf bytes.Buffer
+	h := slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})
+	l := slog.New(NewSamplingHandler(h, SamplingOptions{First: 2, Thereafter: 3, Tick: time.Hour}))
+
+	// 派生的 logger 与原 logger 共享计数
+	derived := l.With("group", "scores")
+	for i := 0; i < 5; i++ {
+		l.Warn("peer failed")
+		derived.Warn("peer failed")
+	}
+	for i := 0; i < 3; i++ {
+		l.Error("fatal")
+	}
+	// 共 10 条 "peer failed"：前 2 条，之后第 5、8 条
+	if got := strings.Count(buf.String(), "peer failed"); got != 4 {
+		t.Fatalf("expected 4 sampled records, got %d:\n%s", got, buf.String())
+	}
+	if got := strings.Count(buf.String(), "fatal"); got != 3 {
+		t.Fatalf("expected errors not to be sampled, got %d", got)
+	}
+}
+
+// Level 为 slog.LevelInfo（零值）时同样生效，Info 及以上的记录都不采样
+func TestSamplingLevelInfo(t *testing.T) {
+	var buf bytes.Buffer
+	h := slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})
+	l := slog.New(NewSamplingHandler(h, SamplingOptions{Level: slog.LevelInfo, First: 1, Thereafter: 100, Tick: time.Hour}))
+	for i := 0; i < 5; i++ {
+		l.Info("loaded")
+		l.Debug("cache hit")
+	}
+	if got := strings.Count(buf.String(), "loaded"); got != 5 {
+		t.Fatalf("expected info records not to be sampled, got %d", got)
+	}
+	if got := strings.Count(buf.String(), "cache hit"); got != 1 {
+		t.Fatalf("expected debug records to be sampled, got %d", got)
+	}
+}
+
+func TestSamplingResetsEachTick(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewSamplingHandler(slog.NewTextHandler(&buf, nil), SamplingOptions{First: 1, Thereafter: 100, Tick: time.Second})
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		r := slog.NewRecord(now.Add(time.Duration(i)*time.Second), slog.LevelWarn, "tick", 0)
+		if err := h.Handle(context.Background(), r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := strings.Count(buf.String(), "tick"); got != 3 {
+		t.Fatalf("expected one record per tick, got %d", got)
+	}
+}
+
+func TestDefaultIsQuiet(t *testing.T) {
+	if Default().Enabled(context.Background(), slog.LevelInfo) {
+		t.Fatal("default logger should not log below warn")
+	}
+	old := Default()
+	defer SetDefault(old)
+	SetDefault(nil)
+	if Default().Enabled(context.Background(), slog.LevelError) {
+		t.Fatal("SetDefault(nil) should discard all records")
+	}
+}
@@ -0,0 +1,231 @@
+// Package metrics 以 Prometheus 文本格式（version 0.0.4）导出指标，不依赖 Prometheus 客户端库。
+// 各个组件实现 Collector，在每次抓取时把当前的计数写入 Writer。
+package metrics
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ContentType 是文本格式的 Content-Type
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Collector 在每次抓取时把自己的指标写入 w
+type Collector interface {
+	Collect(w *Writer)
+}
+
+// CollectorFunc 用函数实现 Collector
+type CollectorFunc func(w *Writer)
+
+// Collect implements Collector interface function
+func (f CollectorFunc) Collect(w *Writer) {
+	f(w)
+}
+
+// Registry 保存一组 Collector，并作为 /metrics 的 http.Handler
+type Registry struct {
+	mu         sync.Mutex
+	collectors []Collector
+}
+
+// NewRegistry 创建一个空的 Registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register 添加 Collector
+func (r *Registry) Register(cs ...Collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, cs...)
+}
+
+// Gather 调用所有 Collector，把结果以文本格式写入 out
+func (r *Registry) Gather(out io.Writer) error {
+	r.mu.Lock()
+	collectors := append([]Collector(nil), r.collectors...)
+	r.mu.Unlock()
+
+	w := newWriter()
+	for _, c := range collectors {
+		c.Collect(w)
+	}
+	_, err := w.writeTo(out)
+	return err
+}
+
+// ServeHTTP 实现 http.Handler
+func (r *Registry) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	var buf bytes.Buffer
+	if err := r.Gather(&buf); err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	rw.Header().Set("Content-Type", ContentType)
+	rw.Write(buf.Bytes())
+}
+
+// family 是同名的一组样本，文本格式要求它们连续出现，并且只有一行 HELP 和 TYPE
+type family struct {
+	name, help, typ string
+	samples         bytes.Buffer
+}
+
+// Writer 收集一次抓取的样本，按指标名分组后输出。
+// 同一个指标可以由多个 Collector 分多次写入，例如同一进程中的多个节点。
+type Writer struct {
+	families []*family
+	byName   map[string]*family
+}
+
+func newWriter() *Writer {
+	return &Writer{byName: make(map[string]*family)}
+}
+
+func (w *Writer) family(name, help, typ string) *family {
+	f, ok := w.byName[name]
+	if !ok {
+		f = &family{name: name, help: help, typ: typ}
+		w.families = append(w.families, f)
+		w.byName[name] = f
+	}
+	return f
+}
+
+// Counter 写入一个计数器样本，labels 为交替出现的标签名和标签值
+func (w *Writer) Counter(name, help string, value float64, labels ...string) {
+	writeSample(&w.family(name, help, "counter").samples, name, labels, "", "", value)
+}
+
+// Gauge 写入一个仪表盘样本，labels 为交替出现的标签名和标签值
+func (w *Writer) Gauge(name, help string, value float64, labels ...string) {
+	writeSample(&w.family(name, help, "gauge").samples, name, labels, "", "", value)
+}
+
+// Histogram 写入直方图的累计桶、总和与总数
+func (w *Writer) Histogram(name, help string, h *Histogram, labels ...string) {
+	buf := &w.family(name, help, "histogram").samples
+	counts, sum := h.snapshot()
+	var cumulative uint64
+	for i, bound := range h.bounds {
+		cumulative += counts[i]
+		writeSample(buf, name+"_bucket", labels, "le", formatFloat(bound), float64(cumulative))
+	}
+	cumulative += counts[len(h.bounds)]
+	writeSample(buf, name+"_bucket", labels, "le", "+Inf", float64(cumulative))
+	writeSample(buf, name+"_sum", labels, "", "", sum)
+	writeSample(buf, name+"_count", labels, "", "", float64(cumulative))
+}
+
+func (w *Writer) writeTo(out io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	for _, f := range w.families {
+		buf.WriteString("# HELP " + f.name + " " + escapeHelp(f.help) + "\n")
+		buf.WriteString("# TYPE " + f.name + " " + f.typ + "\n")
+		buf.Write(f.samples.Bytes())
+	}
+	return buf.WriteTo(out)
+}
+
+// writeSample 写入一行样本，extraName 不为空时追加在 labels 之后（用于直方图的 le）
+func writeSample(buf *bytes.Buffer, name string, labels []string, extraName, extraValue string, value float64) {
+	buf.WriteString(name)
+	if len(labels) >= 2 || extraName != "" {
+		buf.WriteByte('{')
+		sep := ""
+		for i := 0; i+1 < len(labels); i += 2 {
+			buf.WriteString(sep + labels[i] + `="` + escapeLabel(labels[i+1]) + `"`)
+			sep = ","
+		}
+		if extraName != "" {
+			buf.WriteString(sep + extraName + `="` + extraValue + `"`)
+		}
+		buf.WriteByte('}')
+	}
+	buf.WriteByte(' ')
+	buf.WriteString(formatFloat(value))
+	buf.WriteByte('\n')
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// DefaultBuckets 是以秒为单位的默认延迟桶，覆盖 0.5ms 到 10s
+var DefaultBuckets = []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Histogram 是桶边界固定的直方图，Observe 只使用原子操作，可以在热路径上并发调用
+type Histogram struct {
+	bounds []float64       // 递增的桶上界
+	counts []atomic.Uint64 // 落在每个桶中的次数，最后一个是 +Inf 桶
+	sum    atomic.Uint64   // 所有观测值之和，按 float64 的位存储
+}
+
+// NewHistogram 创建桶上界为 bounds 的直方图，bounds 为空时使用 DefaultBuckets
+func NewHistogram(bounds ...float64) *Histogram {
+	if len(bounds) == 0 {
+		bounds = DefaultBuckets
+	}
+	bounds = append([]float64(nil), bounds...)
+	sort.Float64s(bounds)
+	return &Histogram{bounds: bounds, counts: make([]atomic.Uint64, len(bounds)+1)}
+}
+
+// Observe 记录一个观测值
+func (h *Histogram) Observe(v float64) {
+	h.counts[sort.SearchFloat64s(h.bounds, v)].Add(1)
+	for {
+		old := h.sum.Load()
+		if h.sum.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+v)) {
+			return
+		}
+	}
+}
+
+// ObserveDuration 以秒为单位记录 d
+func (h *Histogram) ObserveDuration(d time.Duration) {
+	h.Observe(d.Seconds())
+}
+
+// Count 返回观测次数
+func (h *Histogram) Count() uint64 {
+	var n uint64
+	for i := range h.counts {
+		n += h.counts[i].Load()
+	}
+	return n
+}
+
+// snapshot 返回每个桶（非累计）的次数和观测值之和
+func (h *Histogram) snapshot() ([]uint64, float64) {
+	counts := make([]uint64, len(h.counts))
+	for i := range h.counts {
+		counts[i] = h.counts[i].Load()
+	}
+	return counts, math.Float64frombits(h.sum.Load())
+}
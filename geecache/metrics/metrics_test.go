@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestExposition(t *testing.T) {
+	h := NewHistogram(0.1, 1)
+	for _, v := range []float64{0.05, 0.5, 0.5, 3} {
+		h.Observe(v)
+	}
+	r := NewRegistry()
+	r.Register(CollectorFunc(func(w *Writer) {
+		w.Counter("requests_total", "Requests.\nSecond line.", 3, "path", `a"b\c`)
+		w.Gauge("temperature", "Temperature.", 21.5)
+		w.Histogram("latency_seconds", "Latency.", h, "peer", "x")
+	}))
+	// 第二个 Collector 写入已有的指标，样本应与第一个的合并在一起
+	r.Register(CollectorFunc(func(w *Writer) {
+		w.Counter("requests_total", "Requests.\nSecond line.", 4, "path", "/")
+	}))
+
+	var buf bytes.Buffer
+	if err := r.Gather(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP requests_total Requests.\nSecond line.
+# TYPE requests_total counter
+requests_total{path="a\"b\\c"} 3
+requests_total{path="/"} 4
+# HELP temperature Temperature.
+# TYPE temperature gauge
+temperature 21.5
+# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{peer="x",le="0.1"} 1
+latency_seconds_bucket{peer="x",le="1"} 3
+latency_seconds_bucket{peer="x",le="+Inf"} 4
+latency_seconds_sum{peer="x"} 4.05
+latency_seconds_count{peer="x"} 4
+`
+	if got := buf.String(); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if h.Count() != 4 {
+		t.Fatalf("Count = %d, want 4", h.Count())
+	}
+}
+
+func TestHistogramBoundary(t *testing.T) {
+	// 桶的上界是闭区间：等于上界的观测值落在该桶中
+	h := NewHistogram(1, 2)
+	h.Observe(1)
+	h.Observe(2)
+	counts, _ := h.snapshot()
+	if counts[0] != 1 || counts[1] != 1 || counts[2] != 0 {
+		t.Fatalf("counts = %v", counts)
+	}
+	for v, want := range map[float64]string{1e-4: "0.0001", 2.5: "2.5", 1e21: "1e+21"} {
+		if got := formatFloat(v); got != want {
+			t.Fatalf("formatFloat(%v) = %s, want %s", v, got, want)
+		}
+	}
+}
@@ -3,10 +3,13 @@ package tests
 import (
 	"GeeCache/geecache/core"
 	"GeeCache/geecache/interfaces"
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"reflect"
 	"testing"
+	"time"
 )
 
 type Getter interfaces.Getter
@@ -75,3 +78,156 @@ func TestGet(t *testing.T) {
 	}
 
 }
+
+// 测试 Group 的默认 TTL 以及 loader 返回的单个 key 的 TTL
+func TestGetWithTTL(t *testing.T) {
+	loads := 0
+	gee := core.NewGroup("ttl", 2<<10, interfaces.TTLGetterFunc(
+		func(key string) ([]byte, time.Duration, error) {
+			loads++
+			if key == "short" {
+				return []byte(key), 20 * time.Millisecond, nil
+			}
+			return []byte(key), 0, nil
+		}), "lru")
+	gee.SetTTL(time.Hour)
+
+	for _, key := range []string{"short", "long"} {
+		if _, err := gee.Get(key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(30 * time.Millisecond)
+	for _, key := range []string{"short", "long"} {
+		if view, err := gee.Get(key); err != nil || view.String() != key {
+			t.Fatalf("failed to get %s", key)
+		}
+	}
+	// 只有 short 过期后被重新加载
+	if loads != 3 {
+		t.Fatalf("expected 3 loads, got %d", loads)
+	}
+}
+
+type ctxKey struct{}
+
+// 同时支持 ctx 和 TTL 的回调既收到调用方的 ctx，返回的 TTL 也生效
+func TestGetWithTTLContext(t *testing.T) {
+	loads := 0
+	gee := core.NewRegistry().NewGroup("ttl-ctx", 2<<10, interfaces.TTLGetterWithContextFunc(
+		func(ctx context.Context, key string) ([]byte, time.Duration, error) {
+			if ctx.Value(ctxKey{}) == nil {
+				return nil, 0, fmt.Errorf("ctx not propagated")
+			}
+			loads++
+			return []byte(key), 20 * time.Millisecond, nil
+		}), "lru")
+	gee.SetTTL(time.Hour)
+
+	ctx, cancel := context.WithTimeout(context.WithValue(context.Background(), ctxKey{}, "caller"), time.Second)
+	defer cancel()
+	if _, err := gee.GetContext(ctx, "short"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if _, err := gee.GetContext(ctx, "short"); err != nil {
+		t.Fatal(err)
+	}
+	// 回调返回的 TTL 覆盖了 Group 的默认 TTL，因此第二次读取重新加载
+	if loads != 2 {
+		t.Fatalf("expected the per-key ttl to expire the entry, got %d loads", loads)
+	}
+}
+
+// 测试等待同一个 key 的调用方在各自超时后返回，所有调用方都放弃后回调函数的 ctx 被取消
+func TestGetContextDeadline(t *testing.T) {
+	release := make(chan struct{})
+	cancelled := make(chan struct{}, 1)
+	gee := core.NewGroup("ctx", 2<<10, interfaces.GetterWithContextFunc(
+		func(ctx context.Context, key string) ([]byte, error) {
+			select {
+			case <-release:
+				return []byte(key), nil
+			case <-ctx.Done():
+				cancelled <- struct{}{}
+				return nil, ctx.Err()
+			}
+		}), "lru")
+
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+			_, err := gee.GetContext(ctx, "slow")
+			errs <- err
+		}()
+	}
+	for i := 0; i < 2; i++ {
+		if err := <-errs; !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected deadline exceeded, got %v", err)
+		}
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("load was not cancelled after every caller gave up")
+	}
+
+	close(release)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if view, err := gee.GetContext(ctx, "slow"); err != nil || view.String() != "slow" {
+		t.Fatalf("GetContext failed: %v", err)
+	}
+}
+
+// 测试第一个调用方取消后，共享同一次加载的其他调用方仍然拿到结果
+func TestGetContextLeaderCancel(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	gee := core.NewRegistry().NewGroup("ctx-leader", 2<<10, interfaces.GetterWithContextFunc(
+		func(ctx context.Context, key string) ([]byte, error) {
+			close(started)
+			select {
+			case <-release:
+				return []byte(key), nil
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}), "lru")
+
+	leaderCtx, cancelLeader := context.WithCancel(context.Background())
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, err := gee.GetContext(leaderCtx, "hot")
+		leaderErr <- err
+	}()
+	<-started
+
+	type result struct {
+		value string
+		err   error
+	}
+	waiter := make(chan result, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		view, err := gee.GetContext(ctx, "hot")
+		waiter <- result{view.String(), err}
+	}()
+	// 等待第二个调用方加入正在进行的加载
+	for gee.Stats().Misses < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	cancelLeader()
+	if err := <-leaderErr; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the leader to be cancelled, got %v", err)
+	}
+	close(release)
+	if r := <-waiter; r.err != nil || r.value != "hot" {
+		t.Fatalf("waiter got %q, %v; want the loaded value", r.value, r.err)
+	}
+}
@@ -0,0 +1,321 @@
+package tests
+
+import (
+	"GeeCache/geecache/clustertest"
+	"GeeCache/geecache/core"
+	"GeeCache/geecache/distributed"
+	"GeeCache/geecache/interfaces"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// loadCounter 记录每个节点的 getter 被调用的次数
+type loadCounter struct {
+	mu    sync.Mutex
+	loads map[string]int
+}
+
+func (lc *loadCounter) getter(n *clustertest.Node) interfaces.Getter {
+	return interfaces.GetterFunc(func(key string) ([]byte, error) {
+		lc.mu.Lock()
+		defer lc.mu.Unlock()
+		lc.loads[n.Addr]++
+		return []byte("value-" + key), nil
+	})
+}
+
+func (lc *loadCounter) count(addr string) int {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	return lc.loads[addr]
+}
+
+func (lc *loadCounter) total() int {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	total := 0
+	for _, n := range lc.loads {
+		total += n
+	}
+	return total
+}
+
+func newCountingCluster(t *testing.T, group string) (*clustertest.Cluster, *loadCounter) {
+	c := clustertest.New(t, clustertest.Config{Nodes: 3})
+	lc := &loadCounter{loads: make(map[string]int)}
+	c.NewGroup(group, 1<<20, "lru", lc.getter)
+	c.Leader() // 等待集群稳定
+	return c, lc
+}
+
+// remoteKey 返回一个由其他节点负责的 key
+func remoteKey(t *testing.T, n *clustertest.Node) string {
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("key%d", i)
+		if _, ok := n.Pool.PickPeer(key); ok {
+			return key
+		}
+	}
+	t.Fatalf("%s owns every key", n.Addr)
+	return ""
+}
+
+// versionGetter 返回 version 的当前值，每次加载前等待 delay
+func versionGetter(version *atomic.Value, delay time.Duration) interfaces.Getter {
+	return interfaces.GetterFunc(func(key string) ([]byte, error) {
+		time.Sleep(delay)
+		return []byte(version.Load().(string)), nil
+	})
+}
+
+// newVersionedCluster 启动 nodes 个节点，所有节点共用 getter，hotCache 中的数据 hotTTL 后过期
+func newVersionedCluster(t *testing.T, nodes int, group string, getter interfaces.Getter, hotTTL time.Duration) *clustertest.Cluster {
+	c := clustertest.New(t, clustertest.Config{Nodes: nodes})
+	for _, n := range c.Nodes {
+		if _, err := n.NewGroupWithOptions(group, 1<<20, getter, core.WithHotCacheTTL(hotTTL)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c.Leader()
+	return c
+}
+
+// waitForValue 等待所有节点读到 key 的值为 want
+func waitForValue(c *clustertest.Cluster, group, key, want string) {
+	c.WaitFor(2*time.Second, func() bool {
+		for _, n := range c.Nodes {
+			if view, err := n.GetGroup(group).Get(key); err != nil || view.String() != want {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// 每个 key 只由负责它的节点加载一次，其他节点通过 gRPC 从该节点获取
+func TestClusterPeerLoading(t *testing.T) {
+	c, lc := newCountingCluster(t, "cluster-loading")
+
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("key%d", i)
+		for _, n := range c.Nodes {
+			view, err := n.GetGroup("cluster-loading").Get(key)
+			if err != nil {
+				t.Fatalf("%s: get %s: %v", n.Addr, key, err)
+			}
+			if view.String() != "value-"+key {
+				t.Fatalf("%s: get %s = %q", n.Addr, key, view.String())
+			}
+		}
+	}
+	if total := lc.total(); total != 20 {
+		t.Fatalf("expected each key to be loaded once, got %d loads", total)
+	}
+}
+
+// 与负责 key 的节点断开后，节点回退到本地加载
+func TestClusterPartitionFallsBackToLocal(t *testing.T) {
+	c, lc := newCountingCluster(t, "cluster-partition")
+	n := c.Nodes[0]
+	key := remoteKey(t, n)
+
+	c.Isolate(n)
+	view, err := n.GetGroup("cluster-partition").Get(key)
+	if err != nil || view.String() != "value-"+key {
+		t.Fatalf("get %s during partition = %q, %v", key, view.String(), err)
+	}
+	if lc.count(n.Addr) != 1 {
+		t.Fatalf("expected a local load on %s, got %d", n.Addr, lc.count(n.Addr))
+	}
+}
+
+// 丢失的请求同样回退到本地加载，延迟超过调用方的截止时间时返回超时
+func TestClusterDropAndDelay(t *testing.T) {
+	c, lc := newCountingCluster(t, "cluster-faults")
+	n := c.Nodes[0]
+	key := remoteKey(t, n)
+	for _, o := range c.Nodes[1:] {
+		c.Net.Drop(n.Addr, o.Addr, 1)
+	}
+	if _, err := n.GetGroup("cluster-faults").Get(key); err != nil {
+		t.Fatal(err)
+	}
+	if lc.count(n.Addr) != 1 {
+		t.Fatalf("expected a local load on %s after a dropped request, got %d", n.Addr, lc.count(n.Addr))
+	}
+
+	c.Net.Heal()
+	other := remoteKey(t, c.Nodes[1])
+	for _, o := range c.Nodes {
+		if o != c.Nodes[1] {
+			c.Net.Delay(c.Nodes[1].Addr, o.Addr, 200*time.Millisecond)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := c.Nodes[1].GetGroup("cluster-faults").GetContext(ctx, other); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a deadline error from a delayed peer, got %v", err)
+	}
+}
+
+// 领导者被隔离后剩余节点选出新领导者；恢复后旧领导者退位并追上日志
+func TestClusterLeaderFailover(t *testing.T) {
+	c := clustertest.New(t, clustertest.Config{Nodes: 3})
+	old := c.Leader()
+
+	c.Isolate(old)
+	leader := c.Leader(old)
+	cmd := distributed.ClusterCommand{Op: distributed.OpSetGroup, Group: "scores", Config: &distributed.GroupConfig{CacheBytes: 1 << 20, Algorithm: "lru"}}
+	if _, err := leader.Pool.Raft().Propose(context.Background(), cmd.Encode()); err != nil {
+		t.Fatal(err)
+	}
+
+	c.Net.Heal()
+	c.WaitFor(2*time.Second, func() bool {
+		_, isLeader := old.Pool.Raft().State()
+		return !isLeader && old.Pool.Raft().CommitIndex() >= leader.Pool.Raft().CommitIndex()
+	})
+}
+
+// 有界负载把请求转给拥有者之外的节点时，接收方在本地加载，而不是把请求送回拥有者
+func TestClusterBoundedLoadServesForwardedLocally(t *testing.T) {
+	c := clustertest.New(t, clustertest.Config{Nodes: 4})
+	lc := &loadCounter{loads: make(map[string]int)}
+	c.NewGroup("cluster-bounded", 1<<20, "lru", func(n *clustertest.Node) interfaces.Getter {
+		slow := lc.getter(n)
+		return interfaces.GetterFunc(func(key string) ([]byte, error) {
+			time.Sleep(50 * time.Millisecond) // 让请求保持在途，拥有者的负载才会超过上限
+			return slow.Get(key)
+		})
+	})
+	c.Leader()
+
+	n, owner := c.Nodes[0], c.Nodes[1]
+	var keys []string
+	for i := 0; len(keys) < 30; i++ {
+		key := fmt.Sprintf("key%d", i)
+		if peer, ok := n.Pool.PickPeer(key); ok && fmt.Sprint(peer) == owner.Addr {
+			keys = append(keys, key)
+		}
+	}
+	if err := n.Pool.SetBoundedLoad(0.25); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for _, key := range keys {
+		wg.Add(1)
+		go func(key string) {
+			defer wg.Done()
+			if view, err := n.GetGroup("cluster-bounded").Get(key); err != nil || view.String() != "value-"+key {
+				t.Errorf("get %s = %q, %v", key, view.String(), err)
+			}
+		}(key)
+	}
+	wg.Wait()
+
+	// 转给其他节点的请求由接收方加载；以前接收方会把请求送回拥有者，其他节点的加载次数总是 0
+	others := lc.total() - lc.count(owner.Addr) - lc.count(n.Addr)
+	if others == 0 {
+		t.Fatalf("expected forwarded requests to be loaded by the receiving nodes, loads: owner=%d self=%d others=0",
+			lc.count(owner.Addr), lc.count(n.Addr))
+	}
+	if got := lc.count(owner.Addr); got >= len(keys)/2 {
+		t.Fatalf("expected bounded loads to take requests off the owner, owner loaded %d of %d keys", got, len(keys))
+	}
+	if lc.total() != len(keys) {
+		t.Fatalf("expected each key to be loaded once, got %d loads", lc.total())
+	}
+}
+
+// Remove 只通知拥有者和副本节点；5 个节点时至少有一个节点没有收到通知，它 hotCache 中的旧值要在 HotCacheTTL 后过期
+func TestClusterRemoveExpiresHotCopies(t *testing.T) {
+	var version atomic.Value
+	version.Store("v1")
+	c := newVersionedCluster(t, 5, "cluster-remove-hot", versionGetter(&version, 0), 100*time.Millisecond)
+
+	key := "key0"
+	var owner *clustertest.Node
+	for _, n := range c.Nodes {
+		if _, ok := n.Pool.PickPeer(key); !ok {
+			owner = n
+		}
+	}
+	// 其他节点反复读取，直到数据按概率进入各自的 hotCache
+	for _, n := range c.Nodes {
+		if n == owner {
+			continue
+		}
+		g := n.GetGroup("cluster-remove-hot")
+		for i := 0; g.Stats().HotCache.Items == 0; i++ {
+			if i == 1000 {
+				t.Fatalf("%s never admitted %s into its hot cache", n.Addr, key)
+			}
+			if _, err := g.Get(key); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	version.Store("v2")
+	if err := owner.GetGroup("cluster-remove-hot").Remove(key); err != nil {
+		t.Fatal(err)
+	}
+	waitForValue(c, "cluster-remove-hot", key, "v2")
+}
+
+// 有界负载把请求转给拥有者之外的节点时，这些节点把数据放入 hotCache 而不是 mainCache：
+// 它们不一定在 Remove 通知的范围内，Remove 之后旧值在 HotCacheTTL 后过期
+func TestClusterRemoveAfterBoundedLoadSpill(t *testing.T) {
+	var version atomic.Value
+	version.Store("v1")
+	c := newVersionedCluster(t, 5, "cluster-remove-spill", versionGetter(&version, 50*time.Millisecond), 100*time.Millisecond)
+
+	n, owner := c.Nodes[0], c.Nodes[1]
+	var keys []string
+	for i := 0; len(keys) < 30; i++ {
+		key := fmt.Sprintf("key%d", i)
+		if peer, ok := n.Pool.PickPeer(key); ok && fmt.Sprint(peer) == owner.Addr {
+			keys = append(keys, key)
+		}
+	}
+	if err := n.Pool.SetBoundedLoad(0.25); err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for _, key := range keys {
+		wg.Add(1)
+		go func(key string) {
+			defer wg.Done()
+			if _, err := n.GetGroup("cluster-remove-spill").Get(key); err != nil {
+				t.Error(err)
+			}
+		}(key)
+	}
+	wg.Wait()
+
+	// 转发来的 key 不占用接收方 mainCache 的预算
+	for _, o := range c.Nodes {
+		if o == n || o == owner {
+			continue
+		}
+		if s := o.GetGroup("cluster-remove-spill").Stats(); s.MainCache.Items != 0 {
+			t.Fatalf("%s cached %d forwarded keys in its main cache", o.Addr, s.MainCache.Items)
+		}
+	}
+
+	version.Store("v2")
+	for _, key := range keys {
+		if err := n.GetGroup("cluster-remove-spill").Remove(key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, key := range keys {
+		waitForValue(c, "cluster-remove-spill", key, "v2")
+	}
+}
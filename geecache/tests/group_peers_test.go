@@ -0,0 +1,264 @@
+package tests
+
+import (
+	"GeeCache/geecache/core"
+	pb "GeeCache/geecache/geecachepb"
+	"GeeCache/geecache/interfaces"
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakePeer 模拟一个远程节点，记录收到的请求
+type fakePeer struct {
+	mu      sync.Mutex
+	values  map[string]string
+	gets    int
+	deletes []string
+	pushes  map[string]string
+}
+
+func newFakePeer(values map[string]string) *fakePeer {
+	return &fakePeer{values: values}
+}
+
+func (p *fakePeer) Get(ctx context.Context, in *pb.Request, out *pb.Response) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.gets++
+	out.Value = []byte(p.values[in.Key])
+	return nil
+}
+
+func (p *fakePeer) Delete(ctx context.Context, in *pb.Request, out *pb.DeleteResponse) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, out.Removed = p.values[in.Key]
+	delete(p.values, in.Key)
+	p.deletes = append(p.deletes, in.Key)
+	return nil
+}
+
+func (p *fakePeer) Push(ctx context.Context, in *pb.PushRequest, out *pb.PushResponse) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.pushes == nil {
+		p.pushes = make(map[string]string)
+	}
+	p.pushes[in.Key] = string(in.Value)
+	out.Accepted = true
+	return nil
+}
+
+func (p *fakePeer) pushed(key string) (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	v, ok := p.pushes[key]
+	return v, ok
+}
+
+// fakePicker 把所有 key 都交给 owner，副本节点为 replicas
+type fakePicker struct {
+	owner    interfaces.PeerGetter
+	replicas []interfaces.PeerGetter
+}
+
+func (p *fakePicker) PickPeer(key string) (interfaces.PeerGetter, bool) {
+	return p.owner, p.owner != nil
+}
+
+func (p *fakePicker) GetReplicatedPeers(key string, replicas int) []interfaces.PeerGetter {
+	return p.replicas
+}
+
+// SelectPeer 总是选择最后一个副本，方便测试断言
+func (p *fakePicker) SelectPeer(peers []interfaces.PeerGetter) interfaces.PeerGetter {
+	if len(peers) == 0 {
+		return nil
+	}
+	return peers[len(peers)-1]
+}
+
+func TestGroupRemove(t *testing.T) {
+	owner := newFakePeer(map[string]string{"Tom": "630"})
+	replica := newFakePeer(map[string]string{"Tom": "630"})
+	loads := 0
+	gee := core.NewGroup("remove", 2<<10, interfaces.GetterFunc(
+		func(key string) ([]byte, error) {
+			loads++
+			return []byte("local"), nil
+		}), "lru")
+
+	// 本地加载的数据被删除后需要重新加载
+	if _, err := gee.Get("Jack"); err != nil {
+		t.Fatal(err)
+	}
+	if err := gee.Remove("Jack"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gee.Get("Jack"); err != nil || loads != 2 {
+		t.Fatalf("expected Jack to be reloaded after Remove, loads = %d", loads)
+	}
+
+	// 注册节点后，删除操作需要通知拥有者和副本节点，且同一个节点只通知一次
+	gee.RegisterPeers(&fakePicker{owner: owner, replicas: []interfaces.PeerGetter{owner, replica}})
+	if err := gee.Remove("Tom"); err != nil {
+		t.Fatal(err)
+	}
+	if len(owner.deletes) != 1 || len(replica.deletes) != 1 {
+		t.Fatalf("expected one Delete per peer, got owner=%v replica=%v", owner.deletes, replica.deletes)
+	}
+	if _, ok := replica.values["Tom"]; ok {
+		t.Fatalf("replica still holds Tom")
+	}
+}
+
+func TestHotCacheForPeerValues(t *testing.T) {
+	owner := newFakePeer(map[string]string{"Tom": "630"})
+	gee := core.NewGroup("hotcache", 2<<10, interfaces.GetterFunc(
+		func(key string) ([]byte, error) {
+			t.Fatalf("key %s should be loaded from peer", key)
+			return nil, nil
+		}), "lru")
+	gee.RegisterPeers(&fakePicker{owner: owner})
+
+	// 热点 key 从远程节点获取后一定会进入 hotCache
+	for i := 0; i < 101; i++ {
+		gee.IncrementKeyUsage("Tom")
+	}
+	for i := 0; i < 3; i++ {
+		if view, err := gee.Get("Tom"); err != nil || view.String() != "630" {
+			t.Fatalf("failed to get Tom: %v", err)
+		}
+	}
+	if owner.gets != 1 {
+		t.Fatalf("expected 1 peer get, got %d", owner.gets)
+	}
+
+	// 删除后 hotCache 中的副本也要失效
+	gee.RemoveLocal("Tom")
+	owner.values["Tom"] = "631"
+	if view, _ := gee.Get("Tom"); view.String() != "631" {
+		t.Fatalf("expected fresh value after remove, got %s", view.String())
+	}
+}
+
+func TestHotKeyReplication(t *testing.T) {
+	replicas := []*fakePeer{newFakePeer(nil), newFakePeer(nil)}
+	gee := core.NewGroup("replicate", 2<<10, interfaces.GetterFunc(
+		func(key string) ([]byte, error) {
+			return []byte("v-" + key), nil
+		}), "lru")
+	// 本节点是所有 key 的拥有者
+	gee.RegisterPeers(&fakePicker{replicas: []interfaces.PeerGetter{replicas[0], replicas[1]}})
+
+	if _, err := gee.Get("cold"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 101; i++ {
+		if _, err := gee.Get("hot"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for _, r := range replicas {
+		for {
+			if v, ok := r.pushed("hot"); ok {
+				if v != "v-hot" {
+					t.Fatalf("replica received %q", v)
+				}
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("hot key was not pushed to replica")
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		if _, ok := r.pushed("cold"); ok {
+			t.Fatal("cold key should not be replicated")
+		}
+	}
+}
+
+func TestHotKeyReadsSpreadToReplicas(t *testing.T) {
+	owner := newFakePeer(map[string]string{"Tom": "630"})
+	replica := newFakePeer(map[string]string{"Tom": "630"})
+	gee := core.NewGroup("spread", 2<<10, interfaces.GetterFunc(
+		func(key string) ([]byte, error) {
+			return nil, fmt.Errorf("%s should be loaded from peer", key)
+		}), "lru")
+	gee.RegisterPeers(&fakePicker{owner: owner, replicas: []interfaces.PeerGetter{owner, replica}})
+
+	for i := 0; i < 101; i++ {
+		gee.IncrementKeyUsage("Tom")
+	}
+	if view, err := gee.Get("Tom"); err != nil || view.String() != "630" {
+		t.Fatalf("failed to get Tom: %v", err)
+	}
+	if owner.gets != 0 || replica.gets != 1 {
+		t.Fatalf("expected hot read to go to replica, owner=%d replica=%d", owner.gets, replica.gets)
+	}
+}
+
+// groupPeer 把请求直接交给另一个 Group，模拟 gRPC 服务端对 Forwarded 的处理。
+// 每次 Get 先等待 ready，使两个节点的请求同时在途。
+type groupPeer struct {
+	group *core.Group
+	ready *sync.WaitGroup
+}
+
+func (p *groupPeer) Get(ctx context.Context, in *pb.Request, out *pb.Response) error {
+	p.ready.Done()
+	p.ready.Wait()
+	get := p.group.GetContext
+	if in.Forwarded {
+		get = p.group.GetForwarded
+	}
+	view, err := get(ctx, in.Key)
+	out.Value = view.ByteSlice()
+	return err
+}
+
+func (p *groupPeer) Delete(ctx context.Context, in *pb.Request, out *pb.DeleteResponse) error {
+	out.Removed = p.group.RemoveLocal(in.Key)
+	return nil
+}
+
+func (p *groupPeer) Push(ctx context.Context, in *pb.PushRequest, out *pb.PushResponse) error {
+	return nil
+}
+
+// 两个节点同时把同一个热点 key 分散到对方时，转发来的请求在本地加载，不会等待本节点发出的请求
+func TestHotKeySpreadDoesNotDeadlock(t *testing.T) {
+	getter := interfaces.GetterFunc(func(key string) ([]byte, error) {
+		return []byte("630"), nil
+	})
+	a := core.NewRegistry().NewGroup("spread-deadlock", 2<<10, getter, "lru")
+	b := core.NewRegistry().NewGroup("spread-deadlock", 2<<10, getter, "lru")
+	ready := &sync.WaitGroup{}
+	ready.Add(2)
+	toA, toB := &groupPeer{group: a, ready: ready}, &groupPeer{group: b, ready: ready}
+	a.RegisterPeers(&fakePicker{owner: toB, replicas: []interfaces.PeerGetter{toB}})
+	b.RegisterPeers(&fakePicker{owner: toA, replicas: []interfaces.PeerGetter{toA}})
+	for i := 0; i < 101; i++ {
+		a.IncrementKeyUsage("Tom")
+		b.IncrementKeyUsage("Tom")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	var wg sync.WaitGroup
+	for _, g := range []*core.Group{a, b} {
+		wg.Add(1)
+		go func(g *core.Group) {
+			defer wg.Done()
+			if view, err := g.GetContext(ctx, "Tom"); err != nil || view.String() != "630" {
+				t.Errorf("get Tom = %q, %v", view.String(), err)
+			}
+		}(g)
+	}
+	wg.Wait()
+}
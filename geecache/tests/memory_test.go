@@ -0,0 +1,149 @@
+package tests
+
+import (
+	"GeeCache/geecache/cache"
+	"GeeCache/geecache/core"
+	"GeeCache/geecache/data"
+	"GeeCache/geecache/interfaces"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fill 向缓存中写入 n 条 key 加 value 共 100 字节的数据
+func fill(c *cache.ShardedCache, prefix string, n int) {
+	for i := 0; i < n; i++ {
+		key := fmt.Sprintf("%s%06d", prefix, i)
+		c.Add(key, data.ByteView{B: []byte(strings.Repeat("v", 100-len(key)))})
+	}
+}
+
+// 进程级预算超出时，从超出公平份额最多的缓存中淘汰
+func TestAccountantFairShare(t *testing.T) {
+	global := cache.NewAccountant(4<<10, nil)
+	newCache := func() *cache.ShardedCache {
+		c, err := cache.NewShardedCacheWithConfig(cache.ShardedCacheConfig{Shards: 16, CacheBytes: 4 << 10, Algorithm: "lru", Parent: global})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	a, b := newCache(), newCache()
+
+	fill(a, "a", 100)
+	if a.Bytes() > 4<<10 || a.Bytes() < 3<<10 {
+		t.Fatalf("a alone should use most of the global budget, got %d", a.Bytes())
+	}
+	// a 和 b 的预算相同，各自的公平份额是 2KB，b 写入时应该淘汰 a 的数据
+	fill(b, "b", 20)
+	if b.Bytes() != 2000 {
+		t.Fatalf("b should keep everything it wrote, got %d", b.Bytes())
+	}
+	if global.Used() > 4<<10 || global.Used() != a.Bytes()+b.Bytes() {
+		t.Fatalf("global = %d, a = %d, b = %d", global.Used(), a.Bytes(), b.Bytes())
+	}
+
+	// 关闭后不再计入进程级预算
+	a.Close()
+	if global.Used() != b.Bytes() {
+		t.Fatalf("global = %d after closing a, want %d", global.Used(), b.Bytes())
+	}
+}
+
+// 共享预算的 Group 被删除后释放预算
+func TestGroupSharedBudget(t *testing.T) {
+	global := cache.NewAccountant(8<<10, nil)
+	r := core.NewRegistry()
+	getter := interfaces.GetterFunc(func(key string) ([]byte, error) {
+		return []byte(strings.Repeat("v", 100)), nil
+	})
+	var groups []*core.Group
+	for _, name := range []string{"budget-a", "budget-b"} {
+		g, err := r.NewGroupWithOptions(name, 8<<10, getter, core.WithAccountant(global))
+		if err != nil {
+			t.Fatal(err)
+		}
+		groups = append(groups, g)
+	}
+	for i := 0; i < 200; i++ {
+		for _, g := range groups {
+			if _, err := g.Get(fmt.Sprintf("key%d", i)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if used := global.Used(); used > 8<<10 || used != groups[0].Bytes()+groups[1].Bytes() {
+		t.Fatalf("global = %d, groups = %d + %d", used, groups[0].Bytes(), groups[1].Bytes())
+	}
+
+	r.DeleteGroup("budget-a")
+	if global.Used() != groups[1].Bytes() {
+		t.Fatalf("global = %d after deleting a group, want %d", global.Used(), groups[1].Bytes())
+	}
+}
+
+// 同名的 Group 被替换后释放预算，不再计入共享的 Accountant
+func TestReplacedGroupReleasesBudget(t *testing.T) {
+	global := cache.NewAccountant(8<<10, nil)
+	r := core.NewRegistry()
+	getter := interfaces.GetterFunc(func(key string) ([]byte, error) {
+		return []byte(strings.Repeat("v", 100)), nil
+	})
+	old, err := r.NewGroupWithOptions("budget-replaced", 8<<10, getter, core.WithAccountant(global))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		if _, err := old.Get(fmt.Sprintf("key%d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if global.Used() == 0 {
+		t.Fatal("expected the first group to be charged to the shared budget")
+	}
+
+	g, err := r.NewGroupWithOptions("budget-replaced", 8<<10, getter, core.WithAccountant(global))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.GetGroup("budget-replaced") != g {
+		t.Fatal("expected the new group to replace the old one")
+	}
+	if global.Used() != 0 {
+		t.Fatalf("global = %d after replacing a group, want 0", global.Used())
+	}
+	if _, err := g.Get("key"); err != nil {
+		t.Fatal(err)
+	}
+	if global.Used() != g.Bytes() {
+		t.Fatalf("global = %d, want the new group's %d", global.Used(), g.Bytes())
+	}
+}
+
+// 并发写入多个共享预算的缓存时不死锁，合计不超过预算
+func TestAccountantConcurrent(t *testing.T) {
+	global := cache.NewAccountant(16<<10, nil)
+	var caches []*cache.ShardedCache
+	for i := 0; i < 4; i++ {
+		c, err := cache.NewShardedCacheWithConfig(cache.ShardedCacheConfig{Shards: 8, CacheBytes: 8 << 10, Algorithm: "lru", Parent: global})
+		if err != nil {
+			t.Fatal(err)
+		}
+		caches = append(caches, c)
+	}
+	var wg sync.WaitGroup
+	for i, c := range caches {
+		for w := 0; w < 4; w++ {
+			wg.Add(1)
+			go func(c *cache.ShardedCache, prefix string) {
+				defer wg.Done()
+				fill(c, prefix, 500)
+			}(c, fmt.Sprintf("c%dw%d-", i, w))
+		}
+	}
+	wg.Wait()
+	if global.Used() > 16<<10 {
+		t.Fatalf("global budget exceeded: %d", global.Used())
+	}
+}
@@ -0,0 +1,96 @@
+package tests
+
+import (
+	"GeeCache/geecache/metrics"
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// scrape 抓取 /metrics，按 "名字{标签}" 返回每个样本的值
+func scrape(t *testing.T, url string) map[string]float64 {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != metrics.ContentType {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	samples := make(map[string]float64)
+	sc := bufio.NewScanner(resp.Body)
+	for sc.Scan() {
+		line := sc.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.LastIndexByte(line, ' ')
+		v, err := strconv.ParseFloat(line[i+1:], 64)
+		if err != nil {
+			t.Fatalf("bad sample %q: %v", line, err)
+		}
+		samples[line[:i]] = v
+	}
+	return samples
+}
+
+// 节点的 /metrics 包含 Group、节点调用和 Raft 的指标
+func TestNodeMetrics(t *testing.T) {
+	c, _ := newCountingCluster(t, "metrics")
+	n := c.Nodes[0]
+	registry := metrics.NewRegistry()
+	registry.Register(n)
+	srv := httptest.NewServer(registry)
+	defer srv.Close()
+
+	g := n.GetGroup("metrics")
+	key := remoteKey(t, n)
+	g.Get(key)
+	g.Get(key) // 第二次命中 hotCache 或再次从远程加载
+	for i := 0; i < 5; i++ {
+		g.Get(fmt.Sprintf("local%d", i))
+	}
+
+	s := scrape(t, srv.URL)
+	group := `{group="metrics"}`
+	if s["geecache_group_gets_total"+group] != 7 {
+		t.Fatalf("gets = %v", s["geecache_group_gets_total"+group])
+	}
+	ratio := s["geecache_group_hit_ratio"+group]
+	if want := s["geecache_group_hits_total"+group] / 7; ratio != want {
+		t.Fatalf("hit ratio = %v, want %v", ratio, want)
+	}
+	misses := s["geecache_group_misses_total"+group]
+	if s[`geecache_group_load_duration_seconds_count{group="metrics"}`] != misses ||
+		s[`geecache_group_load_duration_seconds_bucket{group="metrics",le="+Inf"}`] != misses {
+		t.Fatalf("load histogram does not match %v misses", misses)
+	}
+	if _, ok := s[`geecache_cache_evictions_total{group="metrics",tier="main",policy="lru"}`]; !ok {
+		t.Fatal("missing eviction counter")
+	}
+	if s[`geecache_ring_members{self="node0"}`] != 3 {
+		t.Fatalf("ring members = %v", s[`geecache_ring_members{self="node0"}`])
+	}
+
+	var rpcs float64
+	for _, peer := range []string{"node1", "node2"} {
+		rpcs += s[fmt.Sprintf(`geecache_peer_rpc_duration_seconds_count{self="node0",peer=%q}`, peer)]
+	}
+	if rpcs < 1 {
+		t.Fatal("expected at least one peer RPC to be recorded")
+	}
+
+	var roles float64
+	for _, state := range []string{"follower", "candidate", "leader"} {
+		roles += s[fmt.Sprintf(`geecache_raft_state{self="node0",state=%q}`, state)]
+	}
+	if roles != 1 || s[`geecache_raft_term{self="node0"}`] < 1 || s[`geecache_raft_members{self="node0"}`] != 3 {
+		t.Fatalf("unexpected raft metrics: roles=%v term=%v members=%v", roles,
+			s[`geecache_raft_term{self="node0"}`], s[`geecache_raft_members{self="node0"}`])
+	}
+}
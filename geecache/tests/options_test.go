@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"GeeCache/geecache/core"
+	"GeeCache/geecache/interfaces"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 不合法的配置返回错误，不会 panic，也不会注册 Group
+func TestNewGroupWithOptionsInvalid(t *testing.T) {
+	getter := interfaces.GetterFunc(func(key string) ([]byte, error) { return []byte(key), nil })
+	tests := []struct {
+		name       string
+		getter     interfaces.Getter
+		cacheBytes int64
+		opts       []core.GroupOption
+		want       string
+	}{
+		{"nil getter", nil, 1024, nil, "nil Getter"},
+		{"negative size", getter, -1, nil, "cache size"},
+		{"unknown policy", getter, 1024, []core.GroupOption{core.WithPolicy("fifo")}, "unsupported cache algorithm"},
+		{"negative shards", getter, 1024, []core.GroupOption{core.WithShards(-1)}, "shard count"},
+		{"negative ttl", getter, 1024, []core.GroupOption{core.WithTTL(-time.Second)}, "ttl"},
+		{"negative hot cache", getter, 1024, []core.GroupOption{core.WithHotCacheBytes(-1)}, "hot cache size"},
+		{"negative hot cache ttl", getter, 1024, []core.GroupOption{core.WithHotCacheTTL(-time.Second)}, "hot cache ttl"},
+		{"negative load timeout", getter, 1024, []core.GroupOption{core.WithLoadTimeout(-time.Second)}, "load timeout"},
+	}
+	r := core.NewRegistry()
+	for _, tt := range tests {
+		g, err := r.NewGroupWithOptions("invalid", tt.cacheBytes, tt.getter, tt.opts...)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected an error containing %q, got %v", tt.name, tt.want, err)
+		}
+		if g != nil {
+			t.Errorf("%s: expected a nil group", tt.name)
+		}
+	}
+	if len(r.ListGroups()) != 0 {
+		t.Fatalf("invalid groups were registered: %v", r.ListGroups())
+	}
+}
+
+// 回调按命中、未命中和加载的顺序调用，TTL 选项对写入的数据生效
+func TestGroupOptionsHooksAndTTL(t *testing.T) {
+	var events []string
+	g, err := core.NewRegistry().NewGroupWithOptions("hooks", 2<<10,
+		interfaces.GetterFunc(func(key string) ([]byte, error) { return []byte(key), nil }),
+		core.WithShards(4),
+		core.WithPolicy("lfu"),
+		core.WithTTL(time.Minute),
+		core.WithHooks(core.GroupHooks{
+			OnHit:  func(key string) { events = append(events, "hit "+key) },
+			OnMiss: func(key string) { events = append(events, "miss "+key) },
+			OnLoad: func(key string, d time.Duration, err error) { events = append(events, "load "+key) },
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.TTL() != time.Minute {
+		t.Fatalf("TTL = %v, want 1m", g.TTL())
+	}
+	g.Get("Tom")
+	g.Get("Tom")
+	want := []string{"miss Tom", "load Tom", "hit Tom"}
+	if strings.Join(events, ",") != strings.Join(want, ",") {
+		t.Fatalf("events = %v, want %v", events, want)
+	}
+}
+
+// 加载超过 LoadTimeout 时返回超时错误
+func TestGroupLoadTimeout(t *testing.T) {
+	g, err := core.NewRegistry().NewGroupWithOptions("load-timeout", 2<<10,
+		interfaces.GetterWithContextFunc(func(ctx context.Context, key string) ([]byte, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}),
+		core.WithLoadTimeout(20*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Get("slow"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a deadline error, got %v", err)
+	}
+}
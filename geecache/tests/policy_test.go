@@ -0,0 +1,186 @@
+package tests
+
+import (
+	"GeeCache/geecache/cache"
+	"GeeCache/geecache/common"
+	"GeeCache/geecache/core"
+	"GeeCache/geecache/data"
+	"GeeCache/geecache/interfaces"
+	"container/list"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fifoPolicy 是一个先进先出的淘汰策略，演示如何在包外实现 interfaces.EvictionPolicy
+type fifoPolicy struct {
+	maxBytes, nbytes int64
+	ll               *list.List
+	items            map[string]*list.Element
+	onEvicted        func(string, common.Value)
+}
+
+type fifoEntry struct {
+	key   string
+	value common.Value
+}
+
+func newFIFOPolicy(maxBytes int64, onEvicted func(string, common.Value)) interfaces.EvictionPolicy {
+	return &fifoPolicy{maxBytes: maxBytes, ll: list.New(), items: make(map[string]*list.Element), onEvicted: onEvicted}
+}
+
+func (p *fifoPolicy) Get(key string) (common.Value, bool) {
+	if ele, ok := p.items[key]; ok {
+		return ele.Value.(*fifoEntry).value, true
+	}
+	return nil, false
+}
+
+func (p *fifoPolicy) Add(key string, value common.Value) {
+	p.Remove(key)
+	p.items[key] = p.ll.PushFront(&fifoEntry{key, value})
+	p.nbytes += int64(len(key) + value.Len())
+	for p.maxBytes != 0 && p.nbytes > p.maxBytes {
+		p.RemoveOldest()
+	}
+}
+
+func (p *fifoPolicy) AddWithExpire(key string, value common.Value, _ time.Time) { p.Add(key, value) }
+
+func (p *fifoPolicy) Remove(key string) bool {
+	ele, ok := p.items[key]
+	if ok {
+		p.remove(ele)
+	}
+	return ok
+}
+
+func (p *fifoPolicy) RemoveOldest() {
+	if ele := p.ll.Back(); ele != nil {
+		p.remove(ele)
+	}
+}
+
+func (p *fifoPolicy) remove(ele *list.Element) {
+	e := p.ll.Remove(ele).(*fifoEntry)
+	delete(p.items, e.key)
+	p.nbytes -= int64(len(e.key) + e.value.Len())
+	if p.onEvicted != nil {
+		p.onEvicted(e.key, e.value)
+	}
+}
+
+func (p *fifoPolicy) RemoveExpired() int { return 0 }
+func (p *fifoPolicy) Len() int           { return p.ll.Len() }
+func (p *fifoPolicy) Bytes() int64       { return p.nbytes }
+
+// sizedFIFOPolicy 在 fifoPolicy 的基础上实现 interfaces.CapacitySetter，记录创建时收到的容量
+type sizedFIFOPolicy struct {
+	*fifoPolicy
+	factoryBytes int64 // 工厂函数收到的 maxBytes
+	capacity     int64 // SetCapacity 设置的容量
+}
+
+func (p *sizedFIFOPolicy) SetCapacity(bytes int64) { p.capacity = bytes }
+
+var (
+	sizedFIFOsMu sync.Mutex
+	sizedFIFOs   []*sizedFIFOPolicy // 创建过的 sizedFIFOPolicy
+)
+
+func newSizedFIFOPolicy(maxBytes int64, onEvicted func(string, common.Value)) interfaces.EvictionPolicy {
+	p := &sizedFIFOPolicy{fifoPolicy: newFIFOPolicy(maxBytes, onEvicted).(*fifoPolicy), factoryBytes: maxBytes}
+	sizedFIFOsMu.Lock()
+	sizedFIFOs = append(sizedFIFOs, p)
+	sizedFIFOsMu.Unlock()
+	return p
+}
+
+func init() {
+	cache.RegisterPolicy("test-fifo", newFIFOPolicy)
+	cache.RegisterPolicy("test-fifo-sized", newSizedFIFOPolicy)
+}
+
+// 注册的策略可以在 NewCache 和 Group 中按名字使用，淘汰由 Accountant 通过 RemoveOldest 驱动
+func TestRegisterPolicy(t *testing.T) {
+	if !slices.Contains(cache.Policies(), "test-fifo") {
+		t.Fatalf("test-fifo is not listed in %v", cache.Policies())
+	}
+	if c := cache.NewCache("test-fifo", 1024); c == nil {
+		t.Fatal("expected NewCache to use the registered policy")
+	}
+
+	var evicted []string
+	g, err := core.NewRegistry().NewGroupWithOptions("fifo-group", 100,
+		interfaces.GetterFunc(func(key string) ([]byte, error) { return []byte("0123456789"), nil }),
+		core.WithShards(1),
+		core.WithPolicy("test-fifo"),
+		core.WithHooks(core.GroupHooks{OnEvict: func(key string, _ data.ByteView) { evicted = append(evicted, key) }}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		key := fmt.Sprintf("key%d", i)
+		if _, err := g.Get(key); err != nil {
+			t.Fatal(err)
+		}
+		// 读取 key0 不会改变先进先出的顺序
+		g.Get("key0")
+	}
+	if len(evicted) == 0 || evicted[0] != "key0" {
+		t.Fatalf("expected key0 to be evicted first, got %v", evicted)
+	}
+	if g.Bytes() > 100 {
+		t.Fatalf("group holds %d bytes, over its 100 byte budget", g.Bytes())
+	}
+}
+
+// 重复注册同一个名字会 panic
+func TestRegisterPolicyDuplicate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic when registering lru twice")
+		}
+	}()
+	cache.RegisterPolicy("lru", newFIFOPolicy)
+}
+
+// 分片缓存以 maxBytes 为 0 创建注册的策略，由 Accountant 限制容量；
+// 实现 interfaces.CapacitySetter 的策略通过 SetCapacity 得到分片的预算份额
+func TestRegisteredPolicyCapacity(t *testing.T) {
+	sizedFIFOsMu.Lock()
+	sizedFIFOs = nil
+	sizedFIFOsMu.Unlock()
+
+	c, err := cache.NewShardedCacheWithConfig(cache.ShardedCacheConfig{Shards: 4, CacheBytes: 1000, Algorithm: "test-fifo-sized"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	sizedFIFOsMu.Lock()
+	defer sizedFIFOsMu.Unlock()
+	shares := 0
+	for _, p := range sizedFIFOs {
+		if p.factoryBytes != 0 {
+			t.Fatalf("factory received maxBytes %d, want 0", p.factoryBytes)
+		}
+		if p.capacity == 250 {
+			shares++
+		}
+	}
+	if shares != 4 {
+		t.Fatalf("expected each of the 4 shards to receive a 250 byte share, got %d", shares)
+	}
+
+	// 策略本身不限制容量，超出预算时由 Accountant 淘汰
+	for i := 0; i < 100; i++ {
+		c.Add(fmt.Sprintf("key%02d", i), data.ByteView{B: []byte("0123456789")})
+	}
+	if c.Bytes() > 1000 {
+		t.Fatalf("cache holds %d bytes, over its 1000 byte budget", c.Bytes())
+	}
+}
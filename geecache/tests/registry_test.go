@@ -0,0 +1,60 @@
+package tests
+
+import (
+	"GeeCache/geecache/core"
+	"GeeCache/geecache/interfaces"
+	"slices"
+	"testing"
+)
+
+// 不同 Registry 中的同名 Group 互不影响，也不会出现在全局的 DefaultRegistry 中
+func TestRegistryIsolation(t *testing.T) {
+	getter := func(value string) interfaces.Getter {
+		return interfaces.GetterFunc(func(key string) ([]byte, error) {
+			return []byte(value), nil
+		})
+	}
+	a, b := core.NewRegistry(), core.NewRegistry()
+	a.NewGroup("registry-users", 2<<10, getter("a"), "lru")
+	b.NewGroup("registry-users", 2<<10, getter("b"), "lru")
+	b.NewGroup("registry-orders", 2<<10, getter("b"), "lru")
+
+	for name, r := range map[string]*core.Registry{"a": a, "b": b} {
+		view, err := r.GetGroup("registry-users").Get("key")
+		if err != nil || view.String() != name {
+			t.Fatalf("registry %s: get = %q, %v", name, view.String(), err)
+		}
+	}
+	if core.GetGroup("registry-users") != nil {
+		t.Fatal("group created in a private registry leaked into DefaultRegistry")
+	}
+	if got := b.ListGroups(); !slices.Equal(got, []string{"registry-orders", "registry-users"}) {
+		t.Fatalf("ListGroups = %v", got)
+	}
+
+	if !b.DeleteGroup("registry-users") {
+		t.Fatal("expected DeleteGroup to report an existing group")
+	}
+	if b.DeleteGroup("registry-users") {
+		t.Fatal("expected a second DeleteGroup to report a missing group")
+	}
+	if b.GetGroup("registry-users") != nil || a.GetGroup("registry-users") == nil {
+		t.Fatal("DeleteGroup removed the wrong group")
+	}
+}
+
+// 包级函数操作 DefaultRegistry
+func TestDefaultRegistry(t *testing.T) {
+	g := core.NewGroup("registry-default", 2<<10, interfaces.GetterFunc(func(key string) ([]byte, error) {
+		return []byte(key), nil
+	}), "lru")
+	if core.DefaultRegistry.GetGroup("registry-default") != g {
+		t.Fatal("core.NewGroup should register in DefaultRegistry")
+	}
+	if !slices.Contains(core.ListGroups(), "registry-default") {
+		t.Fatalf("ListGroups = %v", core.ListGroups())
+	}
+	if !core.DeleteGroup("registry-default") || core.GetGroup("registry-default") != nil {
+		t.Fatal("core.DeleteGroup did not remove the group")
+	}
+}
@@ -11,11 +11,12 @@ import (
 	"GeeCache/geecache/data"
 	"strconv"
 	"testing"
+	"time"
 )
 
 // 测试 ShardedCache 的功能
 func TestShardedCache(t *testing.T) {
-	// 创建一个包含 4 个分片的 ShardedCache，每个分片最大 1MB，使用 LRU 算法
+	// 创建一个包含 4 个分片的 ShardedCache，合计最大 1MB，每个分片的份额为 256KB，使用 LRU 算法
 	shardedCache := cache.NewShardedCache(4, 1024*1024, "lru")
 
 	// 向分片缓存中添加数据
@@ -29,8 +30,8 @@ func TestShardedCache(t *testing.T) {
 	// 检查每个分片的缓存大小是否正确
 	for i := 0; i < shardedCache.NumShards; i++ {
 		shard := shardedCache.Shards[i]
-		if shard.CacheBytes != 1024*1024 {
-			t.Errorf("Shard %d does not have the correct capacity: expected %d, got %d", i, 1024*1024, shard.CacheBytes)
+		if shard.CacheBytes != 1024*1024/4 {
+			t.Errorf("Shard %d does not have the correct capacity: expected %d, got %d", i, 1024*1024/4, shard.CacheBytes)
 		}
 	}
 
@@ -57,6 +58,14 @@ func TestCacheAlgorithm(t *testing.T) {
 	// 测试 LFU 算法
 	lfuCache := cache.NewConcurrentCache(1024*1024, "lfu")
 	testCacheAlgorithm(t, lfuCache)
+
+	// 测试 W-TinyLFU 算法
+	tinyLFUCache := cache.NewConcurrentCache(1024*1024, "wtinylfu")
+	testCacheAlgorithm(t, tinyLFUCache)
+
+	// 测试 ARC 和 2Q 算法
+	testCacheAlgorithm(t, cache.NewConcurrentCache(1024*1024, "arc"))
+	testCacheAlgorithm(t, cache.NewConcurrentCache(1024*1024, "2q"))
 }
 
 // 验证不同缓存淘汰算法下的缓存操作
@@ -108,23 +117,50 @@ func TestGetShard(t *testing.T) {
 	}
 }
 
-// 测试分片缓存的容量
+// 测试分片缓存的容量：所有分片合计不超过配置的容量
 func TestShardedCacheCapacity(t *testing.T) {
-	shardedCache := cache.NewShardedCache(4, 1024*1024, "lfu")
+	for _, algorithm := range []string{"lru", "lfu", "wtinylfu", "arc", "2q"} {
+		const capacity = 2 << 10
+		shardedCache := cache.NewShardedCache(256, capacity, algorithm)
+
+		// 向缓存中添加数据
+		numKeys := 1000
+		var total int64
+		for i := 0; i < numKeys; i++ {
+			key := "key" + strconv.Itoa(i)
+			value := "value" + strconv.Itoa(i)
+			shardedCache.Add(key, data.ByteView{B: []byte(value)})
+			total += int64(len(key) + len(value))
+		}
 
-	// 向缓存中添加数据
-	numKeys := 1000
-	for i := 0; i < numKeys; i++ {
-		key := "key" + strconv.Itoa(i)
-		value := "value" + strconv.Itoa(i)
-		shardedCache.Add(key, data.ByteView{B: []byte(value)})
+		// 检查所有分片的缓存大小之和是否符合预期
+		var sum int64
+		for _, shard := range shardedCache.Shards {
+			sum += shard.Bytes()
+		}
+		if sum != shardedCache.Bytes() {
+			t.Errorf("%s: shards hold %d bytes, accountant reports %d", algorithm, sum, shardedCache.Bytes())
+		}
+		if sum > capacity || sum < capacity/2 {
+			t.Errorf("%s: expected close to %d bytes (of %d added), got %d", algorithm, capacity, total, sum)
+		}
 	}
+}
+
+// 测试后台清理协程回收过期数据
+func TestShardedCacheJanitor(t *testing.T) {
+	shardedCache := cache.NewShardedCache(4, 1024*1024, "lru")
+	shardedCache.StartJanitor(5 * time.Millisecond)
+	defer shardedCache.StopJanitor()
+
+	for i := 0; i < 100; i++ {
+		shardedCache.AddWithExpire("key"+strconv.Itoa(i), data.ByteView{B: []byte("v")}, time.Now().Add(10*time.Millisecond))
+	}
+	time.Sleep(50 * time.Millisecond)
 
-	// 检查每个分片的缓存大小是否符合预期
 	for i := 0; i < shardedCache.NumShards; i++ {
-		shard := shardedCache.Shards[i]
-		if shard.CacheBytes != 1024*1024 {
-			t.Errorf("Shard %d exceeded its capacity: expected %d, got %d", i, 1024*1024, shard.CacheBytes)
+		if n := shardedCache.Shards[i].RemoveExpired(); n != 0 {
+			t.Errorf("shard %d still had %d expired entries", i, n)
 		}
 	}
 }
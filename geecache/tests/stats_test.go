@@ -0,0 +1,120 @@
+package tests
+
+import (
+	"GeeCache/geecache/cache"
+	"GeeCache/geecache/core"
+	"GeeCache/geecache/data"
+	"GeeCache/geecache/interfaces"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 本地命中、未命中、加载失败和 singleflight 合并的计数
+func TestGroupStats(t *testing.T) {
+	release := make(chan struct{})
+	g, err := core.NewRegistry().NewGroupWithOptions("stats", 1<<10, interfaces.GetterFunc(
+		func(key string) ([]byte, error) {
+			switch key {
+			case "missing":
+				return nil, errors.New("not found")
+			case "slow":
+				<-release
+			}
+			return []byte(strings.Repeat("v", 100)), nil
+		}), core.WithShards(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g.Get("Tom")
+	g.Get("Tom")
+	g.Get("missing")
+
+	// 5 个并发请求同一个 key，只加载一次
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			g.Get("slow")
+		}()
+	}
+	for g.Stats().Misses < 7 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond) // 等待所有请求进入 singleflight
+	close(release)
+	wg.Wait()
+
+	s := g.Stats()
+	want := core.GroupStats{Gets: 8, Hits: 1, Misses: 7, LocalLoads: 2, LocalLoadErrors: 1, Dedups: 4}
+	s.MainCache, s.HotCache = cache.Stats{}, cache.Stats{}
+	if s != want {
+		t.Fatalf("stats = %+v, want %+v", s, want)
+	}
+
+	// 超出容量后产生淘汰，条目数和字节数与分片统计一致
+	for i := 0; i < 20; i++ {
+		g.Get(fmt.Sprintf("key%d", i))
+	}
+	main := g.Stats().MainCache
+	if main.Evictions == 0 || main.Bytes > 1<<10 {
+		t.Fatalf("expected evictions within the budget, got %+v", main)
+	}
+}
+
+// 分片统计之和等于 ShardedCache 的合计
+func TestShardedCacheStats(t *testing.T) {
+	c := cache.NewShardedCache(8, 1<<10, "lru")
+	for i := 0; i < 30; i++ {
+		c.Add(fmt.Sprintf("key%03d", i), data.ByteView{B: make([]byte, 94)})
+	}
+	for i := 0; i < 30; i++ {
+		c.Get(fmt.Sprintf("key%03d", i))
+	}
+
+	var sum cache.Stats
+	for _, s := range c.ShardStats() {
+		sum.Hits += s.Hits
+		sum.Misses += s.Misses
+		sum.Evictions += s.Evictions
+		sum.Items += s.Items
+		sum.Bytes += s.Bytes
+	}
+	total := c.Stats()
+	if sum != total {
+		t.Fatalf("sum of shard stats %+v != total %+v", sum, total)
+	}
+	if total.Items != 10 || total.Bytes != 1000 || total.Evictions != 20 || total.Hits != 10 || total.Misses != 20 {
+		t.Fatalf("unexpected stats %+v", total)
+	}
+}
+
+// 从其他节点加载的成功和失败次数记录在发起请求的节点上
+func TestGroupStatsPeerLoads(t *testing.T) {
+	c, _ := newCountingCluster(t, "stats-peers")
+	n := c.Nodes[0]
+	var keys []string
+	for i := 0; len(keys) < 2; i++ {
+		key := fmt.Sprintf("key%d", i)
+		if _, ok := n.Pool.PickPeer(key); ok {
+			keys = append(keys, key)
+		}
+	}
+	g := n.GetGroup("stats-peers")
+	if _, err := g.Get(keys[0]); err != nil {
+		t.Fatal(err)
+	}
+	c.Isolate(n)
+	if _, err := g.Get(keys[1]); err != nil {
+		t.Fatal(err)
+	}
+	s := g.Stats()
+	if s.PeerLoads != 1 || s.PeerErrors != 1 || s.LocalLoads != 1 {
+		t.Fatalf("stats = %+v", s)
+	}
+}
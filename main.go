@@ -136,15 +136,17 @@ package main
 import (
 	"GeeCache/geecache/core"
 	"GeeCache/geecache/distributed" // 引入分布式功能
-	"GeeCache/geecache/geecachepb"
 	"GeeCache/geecache/interfaces"
+	"GeeCache/geecache/logging"
+	"GeeCache/geecache/metrics"
+	"context"
 	"flag"
 	"fmt"
 	"log"
-	"net"
+	"log/slog"
 	"net/http"
-
-	"google.golang.org/grpc"
+	"os"
+	"time"
 )
 
 var db = map[string]string{
@@ -153,44 +155,48 @@ var db = map[string]string{
 	"Sam":  "567",
 }
 
-// createGroup 用于创建缓存组实例
-func createGroup() *core.Group {
-	return core.NewGroup("scores", 2<<10, interfaces.GetterFunc(
+// createGroup 用于在节点上创建缓存组实例
+func createGroup(node *distributed.Node) *core.Group {
+	return node.NewGroup("scores", 2<<10, interfaces.GetterFunc(
 		func(key string) ([]byte, error) {
 			log.Println("[SlowDB] search key", key)
 			if v, ok := db[key]; ok {
 				return []byte(v), nil
 			}
 			return nil, fmt.Errorf("%s not exist", key)
-		}))
+		}), "lru")
 }
 
-// startCacheServer 启动缓存服务器并注册节点池
-func startCacheServer(addr string, addrs []string, gee *core.Group) {
-	// 启动 gRPC 节点池
-	peers := distributed.NewGRPCPool(addr)
-	peers.Set(addrs...)
-	gee.RegisterPeers(peers)
-
-	// 启动 gRPC 服务
-	log.Println("geecache is running at", addr)
-	lis, err := net.Listen("tcp", addr)
-	if err != nil {
-		log.Fatalf("failed to listen: %v", err)
+// startCacheServer 设置节点池的成员并启动缓存服务器。
+// join 为空时以 addrs 为初始成员启动集群，否则通过 join 加入已有的集群，成员列表由 Raft 领导者维护。
+func startCacheServer(node *distributed.Node, addrs []string, join string) {
+	// 节点池在创建 Group 时已经注册
+	peers := node.Pool()
+	if join == "" {
+		peers.Set(addrs...)
+	} else {
+		peers.StartRaft()
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+			if err := peers.JoinCluster(ctx, join, 1); err != nil {
+				log.Fatalf("failed to join the cluster via %s: %v", join, err)
+			}
+			log.Println("joined the cluster via", join)
+		}()
 	}
 
-	grpcServer := grpc.NewServer()
-
-	// 注册 GroupCache 服务
-	geecachepb.RegisterGroupCacheServer(grpcServer, &geecachepb.GroupCacheServer)
-
-	if err := grpcServer.Serve(lis); err != nil {
-		log.Fatalf("failed to serve: %v", err)
-	}
+	// 启动 gRPC 服务，注册 GroupCache 服务
+	log.Println("geecache is running at", node.Addr())
+	log.Fatal(node.ListenAndServe())
 }
 
-// startAPIServer 启动 API 服务器，用于提供 RESTful API 接口
-func startAPIServer(apiAddr string, gee *core.Group) {
+// startAPIServer 启动 API 服务器，用于提供 RESTful API 接口，
+// 并在 /metrics 上以 Prometheus 文本格式导出 node 的指标
+func startAPIServer(apiAddr string, gee *core.Group, node *distributed.Node) {
+	registry := metrics.NewRegistry()
+	registry.Register(node)
+	http.Handle("/metrics", registry)
 	http.Handle("/api", http.HandlerFunc(
 		func(w http.ResponseWriter, r *http.Request) {
 			key := r.URL.Query().Get("key")
@@ -210,12 +216,24 @@ func startAPIServer(apiAddr string, gee *core.Group) {
 func main() {
 	var port int
 	var api bool
+	var join string
+	var logLevel string
 	// 通过命令行参数 -port 设置缓存服务器的端口，默认端口是 8001
 	flag.IntVar(&port, "port", 8001, "Geecache server port")
 	// 通过 -api 参数决定是否启动 API 服务器，默认不启动
 	flag.BoolVar(&api, "api", false, "Start an API server?")
+	// 通过 -join 指定已有集群中任意节点的地址，以新成员身份加入集群
+	flag.StringVar(&join, "join", "", "Address of a cluster member to join through")
+	// 通过 -log-level 设置节点、Group 和 Raft 的日志级别，默认只输出警告和错误
+	flag.StringVar(&logLevel, "log-level", "warn", "Log level: debug, info, warn or error")
 	flag.Parse()
 
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(logLevel)); err != nil {
+		log.Fatalf("invalid -log-level %q: %v", logLevel, err)
+	}
+	logging.SetDefault(logging.New(os.Stderr, level))
+
 	// 定义 API 服务器地址：
 	apiAddr := "http://localhost:9999"
 
@@ -232,14 +250,21 @@ func main() {
 		addrs = append(addrs, v)
 	}
 
-	// 实例化缓存组
-	gee := createGroup()
+	// 缓存服务器地址：
+	addr, ok := addrMap[port]
+	if !ok {
+		addr = fmt.Sprintf("localhost:%d", port)
+	}
+
+	// 创建节点并实例化缓存组
+	node := distributed.NewNode(addr)
+	gee := createGroup(node)
 
 	// 如果命令行参数中指定了 -api，则启动 API 服务器
 	if api {
-		go startAPIServer(apiAddr, gee) // 后台启动 API 服务
+		go startAPIServer(apiAddr, gee, node) // 后台启动 API 服务
 	}
 
-	// 启动缓存服务器：并指定当前节点的地址和其他所有节点的地址
-	startCacheServer(addrMap[port], addrs, gee)
+	// 启动缓存服务器：并指定其他所有节点的地址
+	startCacheServer(node, addrs, join)
 }